@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-link-crawler/services"
+	"io"
+)
+
+// analyzeResult is json output of analyze command
+type analyzeResult struct {
+	Domain   string      `json:"domain"`
+	Analysis interface{} `json:"analysis"`
+}
+
+func runAnalyze(args []string) error {
+	o := &options{}
+	flags := newFlagSet("analyze", "[seeds]", o, "text", "json")
+	addCrawlFlags(flags, o)
+
+	conf, seeds, err := crawlCommand(flags, o, args)
+	if err != nil {
+		return err
+	}
+	conf.CrawlerConfig.Analysis.Enabled = true
+
+	w, err := openOutput(o.output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	results := make([]analyzeResult, 0, len(seeds))
+	err = crawl(conf, o, seeds, func(res services.CrawlerResult) error {
+		if o.format == "json" {
+			results = append(results, analyzeResult{Domain: res.Domain, Analysis: res.Analysis})
+		} else {
+			writeAnalysis(w, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if o.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	return nil
+}
+
+// writeAnalysis writes ranked tables of link analysis
+func writeAnalysis(w io.Writer, res services.CrawlerResult) {
+	if res.Analysis == nil {
+		return
+	}
+
+	fmt.Fprintf(w, "Domain: %s, pages: %d\n", res.Domain, res.Analysis.PagesCount)
+	tables := []struct {
+		name  string
+		pages []services.PageMetrics
+	}{
+		{"top pages", res.Analysis.TopPages},
+		{"orphan pages", res.Analysis.Orphans},
+		{"dead ends", res.Analysis.DeadEnds},
+		{"deep pages", res.Analysis.DeepPages},
+	}
+	for _, t := range tables {
+		fmt.Fprintf(w, "\n%s (%d)\n", t.name, len(t.pages))
+		if len(t.pages) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%4s %10s %5s %5s %6s  %s\n", "#", "pagerank", "in", "out", "clicks", "url")
+		for i, m := range t.pages {
+			fmt.Fprintf(w, "%4d %10.6f %5d %5d %6d  %s\n", i+1, m.PageRank, m.InDegree, m.OutDegree, m.ClickDepth, m.Url)
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-link-crawler/services"
+	"time"
+)
+
+type benchDomain struct {
+	Domain         string  `json:"domain"`
+	Pages          int     `json:"pages"`
+	RequestsPerSec float32 `json:"requests_per_sec"`
+}
+
+type benchResult struct {
+	Domains []benchDomain `json:"domains"`
+	Pages   int           `json:"pages"`
+	// Duration is wall time of all crawls in seconds
+	Duration float64 `json:"duration"`
+	// PagesPerSec is throughput of all crawls together
+	PagesPerSec float64 `json:"pages_per_sec"`
+	// RequestsPerSec is average of domains
+	RequestsPerSec float32 `json:"requests_per_sec"`
+}
+
+func runBench(args []string) error {
+	o := &options{}
+	flags := newFlagSet("bench", "[seeds]", o, "text", "json")
+	addCrawlFlags(flags, o)
+
+	conf, seeds, err := crawlCommand(flags, o, args)
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(o.output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	bench := benchResult{Domains: []benchDomain{}}
+	start := time.Now()
+	err = crawl(conf, o, seeds, func(res services.CrawlerResult) error {
+		bench.Domains = append(bench.Domains, benchDomain{
+			Domain:         res.Domain,
+			Pages:          res.InnerLinksCount,
+			RequestsPerSec: res.RequestsPerSec,
+		})
+		bench.Pages += res.InnerLinksCount
+		bench.RequestsPerSec += res.RequestsPerSec
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bench.Duration = time.Since(start).Seconds()
+	if bench.Duration > 0 {
+		bench.PagesPerSec = float64(bench.Pages) / bench.Duration
+	}
+	if len(bench.Domains) > 0 {
+		bench.RequestsPerSec = bench.RequestsPerSec / float32(len(bench.Domains))
+	}
+
+	if o.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bench)
+	}
+
+	for _, d := range bench.Domains {
+		fmt.Fprintf(w, "Domain: %s, pages: %d, req/sec: %.2f\n", d.Domain, d.Pages, d.RequestsPerSec)
+	}
+	fmt.Fprintf(w, "pages: %d, duration: %.2fs, pages/sec: %.2f, requests/sec: %.2f\n", bench.Pages, bench.Duration, bench.PagesPerSec, bench.RequestsPerSec)
+
+	return nil
+}
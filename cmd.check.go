@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"go-link-crawler/log"
+	"go-link-crawler/services"
+)
+
+// checkResult is json output of check command
+type checkResult struct {
+	Domain            string      `json:"domain"`
+	BrokenLinks       interface{} `json:"broken_links"`
+	DeadExternalLinks interface{} `json:"dead_external_links,omitempty"`
+}
+
+func runCheck(args []string) error {
+	o := &options{}
+	flags := newFlagSet("check", "[seeds]", o, "text", "json")
+	addCrawlFlags(flags, o)
+
+	conf, seeds, err := crawlCommand(flags, o, args)
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(o.output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	broken := 0
+	results := make([]checkResult, 0, len(seeds))
+	err = crawl(conf, o, seeds, func(res services.CrawlerResult) error {
+		broken += len(res.BrokenLinks) + len(res.DeadExternalLinks)
+		if o.format == "json" {
+			results = append(results, checkResult{
+				Domain:            res.Domain,
+				BrokenLinks:       res.BrokenLinks,
+				DeadExternalLinks: res.DeadExternalLinks,
+			})
+		} else {
+			writeBrokenLinks(w, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if o.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	}
+
+	if broken > 0 {
+		log.Errorf("check failed, broken links count: %d", broken)
+		return errCheckFailed
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"go-link-crawler/services"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+func runCrawl(args []string) error {
+	o := &options{}
+	flags := newFlagSet("crawl", "[seeds]", o, "text", "json", "jsonl")
+	addCrawlFlags(flags, o)
+
+	conf, seeds, err := crawlCommand(flags, o, args)
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(o.output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	var req float32
+	count := 0
+	err = crawl(conf, o, seeds, func(res services.CrawlerResult) error {
+		if res.Graph != nil && conf.CrawlerConfig.Graph.Dir != "" {
+			exportGraph(conf.CrawlerConfig.Graph, res)
+		}
+
+		var err error
+		switch o.format {
+		case "json":
+			err = writeJSONItem(w, res, count)
+		case "jsonl":
+			err = json.NewEncoder(w).Encode(res)
+		default:
+			writeSummary(w, res)
+		}
+
+		count++
+		req = req + res.RequestsPerSec
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	switch o.format {
+	case "json":
+		if count == 0 {
+			fmt.Fprint(w, "[")
+		}
+		fmt.Fprintln(w, "]")
+	case "text":
+		if count > 0 {
+			req = req / float32(count)
+		}
+		fmt.Fprintf(w, "requests/sec: %.2f\n", req)
+	}
+
+	return nil
+}
+
+// writeJSONItem writes i-th item of json array, array is closed by caller
+func writeJSONItem(w io.Writer, v interface{}, i int) error {
+	j, err := json.MarshalIndent(v, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	if i == 0 {
+		fmt.Fprint(w, "[\n  ")
+	} else {
+		fmt.Fprint(w, ",\n  ")
+	}
+	_, err = w.Write(j)
+	return err
+}
+
+func writeSummary(w io.Writer, res services.CrawlerResult) {
+	fmt.Fprintf(w, "Domain: %s, Links count: %d, External links count: %d, req/sec: %.2f\n", res.Domain, res.InnerLinksCount, res.ExternalLinksCount, res.RequestsPerSec)
+	if res.Interrupted {
+		fmt.Fprintf(w, "Domain: %s, crawl was interrupted, result is partial\n", res.Domain)
+	}
+	if res.SitemapXml != nil {
+		fmt.Fprintf(w, "Domain: %s, Sitemap links count: %d, Orphan pages count: %d, Unlisted pages count: %d\n", res.Domain, res.SitemapXml.ListedCount, len(res.SitemapXml.OrphanPages), len(res.SitemapXml.UnlistedPages))
+	}
+	if res.Analysis != nil {
+		for i, m := range res.Analysis.TopPages {
+			fmt.Fprintf(w, "Domain: %s, #%d PageRank: %.4f, in: %d, out: %d, click depth: %d, url: %s\n", res.Domain, i+1, m.PageRank, m.InDegree, m.OutDegree, m.ClickDepth, m.Url)
+		}
+		fmt.Fprintf(w, "Domain: %s, Orphan pages count: %d, Dead ends count: %d, Deep pages count: %d\n", res.Domain, len(res.Analysis.Orphans), len(res.Analysis.DeadEnds), len(res.Analysis.DeepPages))
+	}
+	for _, f := range res.SeoFindings {
+		fmt.Fprintf(w, "Domain: %s, SEO %s %s: %s %s\n", res.Domain, f.Severity, f.Check, f.Url, f.Message)
+	}
+	for _, f := range res.AccessibilityFindings {
+		fmt.Fprintf(w, "Domain: %s, Accessibility %s %s: %s %s\n", res.Domain, f.Severity, f.Check, f.Url, f.Message)
+	}
+	writeBrokenLinks(w, res)
+	if res.Security != nil {
+		fmt.Fprintf(w, "Domain: %s, Mixed content count: %d, Insecure links count: %d, No https redirect count: %d\n", res.Domain, len(res.Security.MixedContent), len(res.Security.InsecureLinks), len(res.Security.HttpsRedirects))
+	}
+	if res.SecurityHeaders != nil {
+		for _, h := range res.SecurityHeaders.Hosts {
+			fmt.Fprintf(w, "Domain: %s, Host: %s, Security header issues: %v\n", res.Domain, h.Host, h.Issues)
+		}
+		fmt.Fprintf(w, "Domain: %s, Security header deviations count: %d\n", res.Domain, len(res.SecurityHeaders.Deviations))
+	}
+	for _, t := range res.Traps {
+		fmt.Fprintf(w, "Domain: %s, suspected trap %s: %s, blocked urls: %d, samples: %v\n", res.Domain, t.Reason, t.Pattern, t.Count, t.Samples)
+	}
+}
+
+func writeBrokenLinks(w io.Writer, res services.CrawlerResult) {
+	for _, b := range res.BrokenLinks {
+		if b.Fragment != "" {
+			fmt.Fprintf(w, "Domain: %s, broken anchor: %s#%s, referrers: %v\n", res.Domain, b.Url, b.Fragment, b.Referrers)
+		} else {
+			fmt.Fprintf(w, "Domain: %s, broken link: %s, status: %d, error: %s, referrers: %v\n", res.Domain, b.Url, b.StatusCode, b.Error, b.Referrers)
+		}
+	}
+	for _, b := range res.DeadExternalLinks {
+		fmt.Fprintf(w, "Domain: %s, dead external link: %s, status: %d, error: %s, referrers: %v\n", res.Domain, b.Url, b.StatusCode, b.Error, b.Referrers)
+	}
+}
+
+func exportGraph(conf config.GraphConfig, res services.CrawlerResult) {
+	if err := os.MkdirAll(conf.Dir, 0755); err != nil {
+		log.Errorf("cannot create graph dir %s err: %v", conf.Dir, err)
+		return
+	}
+
+	for _, format := range conf.Formats {
+		path := filepath.Join(conf.Dir, res.Domain+"."+services.GraphFormatExt(format))
+		if err := writeGraph(path, format, res); err != nil {
+			log.Errorf("graph export to %s err: %v", path, err)
+		}
+	}
+}
+
+func writeGraph(path, format string, res services.CrawlerResult) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return res.Graph.Export(format, f)
+}
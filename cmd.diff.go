@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-crawler/services"
+	"io"
+	"io/ioutil"
+	"sort"
+)
+
+type statusChange struct {
+	Url       string `json:"url"`
+	OldStatus int    `json:"old_status"`
+	NewStatus int    `json:"new_status"`
+}
+
+// resultDiff is difference of two crawls of domain
+type resultDiff struct {
+	Domain           string         `json:"domain"`
+	AddedPages       []string       `json:"added_pages"`
+	RemovedPages     []string       `json:"removed_pages"`
+	StatusChanges    []statusChange `json:"status_changes"`
+	NewBrokenLinks   []string       `json:"new_broken_links"`
+	FixedBrokenLinks []string       `json:"fixed_broken_links"`
+}
+
+func runDiff(args []string) error {
+	o := &options{}
+	flags := newFlagSet("diff", "<old result> <new result>", o, "text", "json")
+	if err := parseFlags(flags, o, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return errors.New("two result files are required")
+	}
+
+	oldResults, err := loadResults(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	newResults, err := loadResults(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	w, err := openOutput(o.output)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	diffs := diffResults(oldResults, newResults)
+	if o.format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diffs)
+	}
+
+	for _, d := range diffs {
+		for _, l := range d.AddedPages {
+			fmt.Fprintf(w, "Domain: %s, + page: %s\n", d.Domain, l)
+		}
+		for _, l := range d.RemovedPages {
+			fmt.Fprintf(w, "Domain: %s, - page: %s\n", d.Domain, l)
+		}
+		for _, c := range d.StatusChanges {
+			fmt.Fprintf(w, "Domain: %s, status: %s %d -> %d\n", d.Domain, c.Url, c.OldStatus, c.NewStatus)
+		}
+		for _, l := range d.NewBrokenLinks {
+			fmt.Fprintf(w, "Domain: %s, + broken link: %s\n", d.Domain, l)
+		}
+		for _, l := range d.FixedBrokenLinks {
+			fmt.Fprintf(w, "Domain: %s, - broken link: %s\n", d.Domain, l)
+		}
+	}
+
+	return nil
+}
+
+// loadResults reads json array, json lines or a single result
+func loadResults(path string) ([]services.CrawlerResult, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]services.CrawlerResult, 0)
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &res); err != nil {
+			return nil, fmt.Errorf("cannot parse %s err: %v", path, err)
+		}
+		return res, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var r services.CrawlerResult
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("cannot parse %s err: %v", path, err)
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// diffResults compares results of the same domains, domains of one side only are compared with empty result
+func diffResults(oldResults, newResults []services.CrawlerResult) []resultDiff {
+	domains := make([]string, 0)
+	oldByDomain := make(map[string]services.CrawlerResult)
+	newByDomain := make(map[string]services.CrawlerResult)
+	for _, r := range oldResults {
+		oldByDomain[r.Domain] = r
+		domains = append(domains, r.Domain)
+	}
+	for _, r := range newResults {
+		if _, ok := oldByDomain[r.Domain]; !ok {
+			domains = append(domains, r.Domain)
+		}
+		newByDomain[r.Domain] = r
+	}
+	sort.Strings(domains)
+
+	res := make([]resultDiff, 0, len(domains))
+	for _, domain := range domains {
+		oldRes, newRes := oldByDomain[domain], newByDomain[domain]
+		d := resultDiff{
+			Domain:        domain,
+			AddedPages:    []string{},
+			RemovedPages:  []string{},
+			StatusChanges: []statusChange{},
+		}
+
+		for l, p := range newRes.Pages {
+			if old, ok := oldRes.Pages[l]; !ok {
+				d.AddedPages = append(d.AddedPages, l)
+			} else if old.StatusCode != p.StatusCode {
+				d.StatusChanges = append(d.StatusChanges, statusChange{Url: l, OldStatus: old.StatusCode, NewStatus: p.StatusCode})
+			}
+		}
+		for l := range oldRes.Pages {
+			if _, ok := newRes.Pages[l]; !ok {
+				d.RemovedPages = append(d.RemovedPages, l)
+			}
+		}
+
+		oldBroken, newBroken := brokenLinkKeys(oldRes), brokenLinkKeys(newRes)
+		d.NewBrokenLinks = subtractKeys(newBroken, oldBroken)
+		d.FixedBrokenLinks = subtractKeys(oldBroken, newBroken)
+
+		sort.Strings(d.AddedPages)
+		sort.Strings(d.RemovedPages)
+		sort.Slice(d.StatusChanges, func(i, j int) bool { return d.StatusChanges[i].Url < d.StatusChanges[j].Url })
+
+		res = append(res, d)
+	}
+
+	return res
+}
+
+func brokenLinkKeys(res services.CrawlerResult) map[string]bool {
+	keys := make(map[string]bool)
+	for _, b := range res.BrokenLinks {
+		if b.Fragment != "" {
+			keys[b.Url+"#"+b.Fragment] = true
+		} else {
+			keys[b.Url] = true
+		}
+	}
+	for _, b := range res.DeadExternalLinks {
+		keys[b.Url] = true
+	}
+	return keys
+}
+
+// subtractKeys returns sorted keys of a which are not in b
+func subtractKeys(a, b map[string]bool) []string {
+	res := make([]string, 0)
+	for k := range a {
+		if !b[k] {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go-link-crawler/services"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+func runExport(args []string) error {
+	o := &options{}
+	flags := newFlagSet("export", "<result>", o, "dot", "gexf", "json", "csv")
+	flags.Lookup("output").Usage = "output dir, - is stdout"
+	if err := parseFlags(flags, o, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("result file is required")
+	}
+
+	results, err := loadResults(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if o.output != "-" {
+		if err := os.MkdirAll(o.output, 0755); err != nil {
+			return err
+		}
+	}
+
+	for _, res := range results {
+		if o.format != "csv" && res.Graph == nil {
+			return fmt.Errorf("result of %s has no link graph, crawl with --graph.enabled", res.Domain)
+		}
+
+		if o.output == "-" {
+			if err := exportResult(os.Stdout, o.format, res); err != nil {
+				return err
+			}
+			continue
+		}
+
+		path := filepath.Join(o.output, res.Domain+"."+services.GraphFormatExt(o.format))
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = exportResult(f, o.format, res)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("export to %s err: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// exportResult writes pages of result as csv or link graph in other formats
+func exportResult(w io.Writer, format string, res services.CrawlerResult) error {
+	if format != "csv" {
+		return res.Graph.Export(format, w)
+	}
+
+	urls := make([]string, 0, len(res.Pages))
+	for l := range res.Pages {
+		urls = append(urls, l)
+	}
+	sort.Strings(urls)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"url", "status_code", "title", "content_hash"})
+	for _, l := range urls {
+		p := res.Pages[l]
+		cw.Write([]string{l, strconv.Itoa(p.StatusCode), p.Title, p.ContentHash})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"go-link-crawler/server"
+	"go-link-crawler/services"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// serveShutdownTimeout is time for requests of api when server is stopped
+const serveShutdownTimeout = 10 * time.Second
+
+func runServe(args []string) error {
+	o := &options{}
+	flags := newFlagSet("serve", "", o)
+	listen := flags.String("listen", ":8080", "address of http api")
+	dataDir := flags.String("data-dir", "./jobs", "dir where jobs and their results are persisted")
+	config.AddCrawlerFlags(flags)
+	if err := parseFlags(flags, o, args); err != nil {
+		return err
+	}
+
+	conf, err := config.Load(o.configPath, flags)
+	if err != nil {
+		return err
+	}
+
+	crawler := services.NewCrawlerService(conf.CrawlerConfig)
+	defer crawler.Close()
+
+	api, err := server.New(conf.CrawlerConfig, crawler, *dataDir)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Addr: *listen, Handler: api}
+
+	// unfinished jobs are started again on the next start
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, stopSignals...)
+	defer signal.Stop(signals)
+	failed := make(chan struct{})   // closed when server cannot listen
+	shutdown := make(chan struct{}) // closed when requests in flight are finished
+	go func() {
+		defer close(shutdown)
+		var sig os.Signal
+		select {
+		case sig = <-signals:
+		case <-failed:
+			return
+		}
+		log.Warnf("%v: stopping http api", sig)
+		ctx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Errorf("http api is not stopped gracefully: %v", err)
+		}
+	}()
+
+	log.Infof("http api listens on %s, jobs are stored in %s", *listen, *dataDir)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		close(failed)
+		return err
+	}
+
+	<-shutdown
+	return nil
+}
@@ -1,13 +1,16 @@
 package config
 
 import (
-	"github.com/fsnotify/fsnotify"
+	"fmt"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go-link-crawler/log"
 )
 
-var (
-	c *Configuration
+// defaults of Load, they are used when neither file nor flags set values
+const (
+	defaultWorkers = 3
+	defaultDepth   = 5
 )
 
 // Configuration struct
@@ -15,29 +18,38 @@ type Configuration struct {
 	CrawlerConfig CrawlerConfig `mapstructure:"crawler"`
 }
 
-func Init() *Configuration {
-	var err error
+// Load reads configuration file and applies flags added by AddCrawlerFlags,
+// missing default file ./config/.go-link-crawler.yaml is not an error
+func Load(path string, flags *pflag.FlagSet) (*Configuration, error) {
 	v := viper.New()
 	v.SetConfigType("yaml")
-	v.SetConfigName(".go-link-crawler")
-	v.AddConfigPath("./config/")
-	v.WatchConfig()
-	v.OnConfigChange(func(e fsnotify.Event) {
-		err = v.ReadInConfig()
-		if err != nil {
-			log.WithTrace("config", "Init").Error("error in parsing configuration file:", err)
-			return
+	// crawl without workers never ends
+	v.SetDefault("crawler.workers", defaultWorkers)
+	v.SetDefault("crawler.depth", defaultDepth)
+	if path != "" {
+		v.SetConfigFile(path)
+	} else {
+		v.SetConfigName(".go-link-crawler")
+		v.AddConfigPath("./config/")
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok || path != "" {
+			return nil, fmt.Errorf("error in parsing configuration file: %v", err)
+		}
+		log.WithTrace("config", "Load").Debug("configuration file is not found, defaults are used")
+	}
+
+	if flags != nil {
+		if err := bindCrawlerFlags(v, flags); err != nil {
+			return nil, err
 		}
-		err = v.Unmarshal(&c)
-	})
-	err = v.ReadInConfig()
-	if err != nil {
-		log.WithTrace("config", "Init").Fatal("error in parsing configuration file:", err)
 	}
-	err = v.Unmarshal(&c)
-	if err != nil {
-		log.WithTrace("config", "Init").Fatal("error in unmarshal configuration file:", err)
+
+	conf := &Configuration{}
+	if err := v.Unmarshal(conf); err != nil {
+		return nil, fmt.Errorf("error in unmarshal configuration file: %v", err)
 	}
 
-	return c
+	return conf, nil
 }
@@ -4,4 +4,180 @@ type CrawlerConfig struct {
 	Depth              int  `mapstructure:"depth"`
 	Workers            int  `mapstructure:"workers"`
 	UseRegexForParsing bool `mapstructure:"use_regex_for_parsing"`
+	// RobotsPolicy for rel=nofollow, meta robots and X-Robots-Tag: respect, ignore (default) or report
+	RobotsPolicy string `mapstructure:"robots_policy"`
+	// StopGraceSec is time for requests in flight of stopped crawl, then they are aborted, 0 means default 10
+	StopGraceSec int `mapstructure:"stop_grace_sec"`
+	// CheckFragments validates #fragment links against element ids of pages
+	CheckFragments bool                `mapstructure:"check_fragments"`
+	Sitemap        SitemapConfig       `mapstructure:"sitemap"`
+	Graph          GraphConfig         `mapstructure:"graph"`
+	Analysis       AnalysisConfig      `mapstructure:"analysis"`
+	Seo            SeoConfig           `mapstructure:"seo"`
+	Canonical      CanonicalConfig     `mapstructure:"canonical"`
+	Duplicates     DuplicatesConfig    `mapstructure:"duplicates"`
+	Traps          TrapsConfig         `mapstructure:"traps"`
+	ExternalCheck  ExternalCheckConfig `mapstructure:"external_check"`
+	Security       SecurityConfig      `mapstructure:"security"`
+	Accessibility  AccessibilityConfig `mapstructure:"accessibility"`
+	Scheduler      SchedulerConfig     `mapstructure:"scheduler"`
+	Frontier       FrontierConfig      `mapstructure:"frontier"`
+	Storage        StorageConfig       `mapstructure:"storage"`
+	// Adaptive changes concurrency of every site during crawl, Workers is initial concurrency then
+	Adaptive AdaptiveConfig `mapstructure:"adaptive"`
+	// Extract rules are evaluated on every page, values are put to pages of result
+	Extract []ExtractRule `mapstructure:"extract"`
+}
+
+// SitemapConfig enables seeding of a crawl from sitemap.xml files
+type SitemapConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxUrls limits how many urls are taken from sitemaps, 0 means no limit
+	MaxUrls int `mapstructure:"max_urls"`
+}
+
+// GraphConfig enables link graph retention and export
+type GraphConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Formats of exported files: dot, gexf, json
+	Formats []string `mapstructure:"formats"`
+	// Dir for exported files, nothing is written if it is empty
+	Dir string `mapstructure:"dir"`
+}
+
+// AnalysisConfig enables internal PageRank and link-equity analysis
+type AnalysisConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// DeepDepth is click depth from which pages are reported as too deep
+	DeepDepth int `mapstructure:"deep_depth"`
+	// Top limits ranked tables, 0 means no limit
+	Top int `mapstructure:"top"`
+}
+
+// SeoConfig enables extraction of page meta data and SEO audit
+type SeoConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TitleMaxLength in characters, 0 means default 60
+	TitleMaxLength int `mapstructure:"title_max_length"`
+	// DescriptionMaxLength in characters, 0 means default 160
+	DescriptionMaxLength int `mapstructure:"description_max_length"`
+}
+
+// CanonicalConfig controls canonical aware traversal
+type CanonicalConfig struct {
+	// Dedup uses canonical url as key of visited set, links of pages
+	// with already known canonical are not followed
+	Dedup bool `mapstructure:"dedup"`
+}
+
+// DuplicatesConfig enables detection of duplicate and near-duplicate content
+type DuplicatesConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Threshold is max SimHash distance of near-duplicates from 1 to 3, 0 means default 3
+	Threshold int `mapstructure:"threshold"`
+	// SkipLinks stops following links from duplicate pages
+	SkipLinks bool `mapstructure:"skip_links"`
+}
+
+// TrapsConfig enables heuristics which stop expansion of crawler traps,
+// zero limits mean defaults
+type TrapsConfig struct {
+	Enabled             bool `mapstructure:"enabled"`
+	MaxUrlLength        int  `mapstructure:"max_url_length"`
+	MaxRepeatedSegments int  `mapstructure:"max_repeated_segments"`
+	// MaxQueryVariants is max count of urls with query per path
+	MaxQueryVariants int `mapstructure:"max_query_variants"`
+	// MaxPagesPerPattern is max count of urls per path pattern, numbers and ids are placeholders in pattern
+	MaxPagesPerPattern int `mapstructure:"max_pages_per_pattern"`
+}
+
+// ExternalCheckConfig enables checks of external links, every unique url is checked once per run
+type ExternalCheckConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Workers is count of concurrent checks, 0 means default 5
+	Workers int `mapstructure:"workers"`
+	// RequestsPerSec limits rate of checks, 0 means no limit
+	RequestsPerSec float64 `mapstructure:"requests_per_sec"`
+	// TimeoutSec of a check request, 0 means default 10 seconds
+	TimeoutSec int `mapstructure:"timeout_sec"`
+}
+
+// SecurityConfig enables security checks of crawled pages
+type SecurityConfig struct {
+	// MixedContent reports http resources and links on https pages
+	// and pages which are not redirected from http to https
+	MixedContent bool `mapstructure:"mixed_content"`
+	// Headers audits security headers and cookie flags of html responses
+	Headers bool `mapstructure:"headers"`
+}
+
+// AccessibilityConfig enables accessibility lint checks of html
+type AccessibilityConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Rules are names of enabled rules, empty means all rules:
+	// html-lang, image-alt, link-text, input-label, duplicate-id, heading-order
+	Rules []string `mapstructure:"rules"`
+}
+
+// ExtractRule is named extraction of page field by css selector or xpath
+type ExtractRule struct {
+	Name string `mapstructure:"name"`
+	// Selector is css selector, either Selector or XPath is required
+	Selector string `mapstructure:"selector"`
+	XPath    string `mapstructure:"xpath"`
+	// Attr is attribute of found element, text of element is used if it is empty
+	Attr string `mapstructure:"attr"`
+	// Regex post-processes value, the first group is taken if regex has groups
+	Regex string `mapstructure:"regex"`
+	// Multi returns all found values instead of the first one
+	Multi bool `mapstructure:"multi"`
+}
+
+// SchedulerConfig limits concurrency of all crawls together, Workers of CrawlerConfig limits every site
+type SchedulerConfig struct {
+	// Workers is global count of concurrent requests shared round-robin by hosts, 0 means no limit
+	Workers int `mapstructure:"workers"`
+	// MaxActiveSites is count of sites crawled at once, other seeds wait in queue, 0 means no limit
+	MaxActiveSites int `mapstructure:"max_active_sites"`
+}
+
+// FrontierConfig sets order of crawl and size of queue of every crawl
+type FrontierConfig struct {
+	// Strategy is bfs (default), dfs or priority (sitemap listed, short paths first)
+	Strategy string `mapstructure:"strategy"`
+	// MaxSize of queue, links found when queue is full are dropped and reported, 0 means no limit
+	MaxSize int `mapstructure:"max_size"`
+	// Boost rules add score to urls of priority strategy
+	Boost []FrontierBoost `mapstructure:"boost"`
+}
+
+// FrontierBoost adds Score to priority of urls matching regex Pattern, negative score lowers priority
+type FrontierBoost struct {
+	Pattern string  `mapstructure:"pattern"`
+	Score   float64 `mapstructure:"score"`
+}
+
+// StorageConfig bounds memory of very large crawls
+type StorageConfig struct {
+	// Visited is visited set of urls: map (default, exact) or bloom (scalable Bloom filter,
+	// urls which are false positives are never crawled)
+	Visited string `mapstructure:"visited"`
+	// FalsePositiveRate of bloom visited set, 0 means default 0.001
+	FalsePositiveRate float64 `mapstructure:"false_positive_rate"`
+	// ExpectedUrls is capacity of the first bloom filter, it grows when it is full, 0 means default 100000
+	ExpectedUrls int `mapstructure:"expected_urls"`
+	// PagesDir spills data of pages to <domain>.pages.jsonl file in dir instead of keeping them in memory,
+	// number is added to name of file if it exists
+	PagesDir string `mapstructure:"pages_dir"`
+}
+
+// AdaptiveConfig grows concurrency of site on fast responses and halves it on slow, failed, 429 and 503 responses
+type AdaptiveConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MinWorkers of every site, 0 means 1
+	MinWorkers int `mapstructure:"min_workers"`
+	// MaxWorkers of every site, 0 means 4 times workers
+	MaxWorkers int `mapstructure:"max_workers"`
+	// TargetLatencyMs is max response time which is not considered slow, 0 means default 1000
+	TargetLatencyMs int `mapstructure:"target_latency_ms"`
 }
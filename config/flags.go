@@ -0,0 +1,68 @@
+package config
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"reflect"
+	"strings"
+)
+
+// flagKeyAnnotation marks flags which override configuration keys
+const flagKeyAnnotation = "config-key"
+
+// AddCrawlerFlags adds flag for every field of CrawlerConfig, names of nested fields are joined by dot,
+// e.g. --external-check.requests-per-sec overrides crawler.external_check.requests_per_sec
+func AddCrawlerFlags(flags *pflag.FlagSet) {
+	addStructFlags(flags, reflect.TypeOf(CrawlerConfig{}), "", "crawler.")
+}
+
+func addStructFlags(flags *pflag.FlagSet, t reflect.Type, namePrefix, keyPrefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" {
+			continue
+		}
+
+		name := namePrefix + strings.Replace(tag, "_", "-", -1)
+		key := keyPrefix + tag
+		usage := "overrides " + key
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			addStructFlags(flags, field.Type, name+".", key+".")
+			continue
+		case reflect.Bool:
+			flags.Bool(name, false, usage)
+		case reflect.Int:
+			flags.Int(name, 0, usage)
+		case reflect.Float64:
+			flags.Float64(name, 0, usage)
+		case reflect.String:
+			flags.String(name, "", usage)
+		case reflect.Slice:
+			// lists of structs can be set only in configuration file
+			if field.Type.Elem().Kind() != reflect.String {
+				continue
+			}
+			flags.StringSlice(name, nil, usage)
+		default:
+			continue
+		}
+
+		flags.SetAnnotation(name, flagKeyAnnotation, []string{key})
+	}
+}
+
+// bindCrawlerFlags makes flags added by AddCrawlerFlags override values of configuration file
+func bindCrawlerFlags(v *viper.Viper, flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		keys, ok := f.Annotations[flagKeyAnnotation]
+		if !ok || err != nil {
+			return
+		}
+		err = v.BindPFlag(keys[0], f)
+	})
+	return err
+}
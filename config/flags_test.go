@@ -0,0 +1,35 @@
+package config
+
+import (
+	"github.com/spf13/pflag"
+	"testing"
+)
+
+func TestCrawlerFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	AddCrawlerFlags(flags)
+
+	err := flags.Parse([]string{"--depth", "2", "--external-check.requests-per-sec", "2.5", "--graph.formats", "dot,json", "--seo.enabled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := Load("", flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := conf.CrawlerConfig
+	if c.Depth != 2 || c.Workers != defaultWorkers {
+		t.Errorf("expected depth 2 and default workers, got %d and %d", c.Depth, c.Workers)
+	}
+	if c.ExternalCheck.RequestsPerSec != 2.5 || !c.Seo.Enabled {
+		t.Errorf("nested flags are not applied: %+v", c)
+	}
+	if len(c.Graph.Formats) != 2 || c.Graph.Formats[1] != "json" {
+		t.Errorf("expected formats [dot json], got %v", c.Graph.Formats)
+	}
+	if flags.Lookup("extract") != nil {
+		t.Errorf("lists of structs should not have flags")
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"github.com/mitchellh/mapstructure"
+)
+
+// Override returns copy of conf with values of overrides, keys are names of configuration file,
+// e.g. {"depth": 2, "external_check": {"enabled": true}}, unknown keys are errors
+func Override(conf CrawlerConfig, overrides map[string]interface{}) (CrawlerConfig, error) {
+	res := conf
+	if len(overrides) == 0 {
+		return res, nil
+	}
+
+	// slices of conf are replaced, not merged
+	res.Extract = append([]ExtractRule{}, conf.Extract...)
+	res.Graph.Formats = append([]string{}, conf.Graph.Formats...)
+	res.Accessibility.Rules = append([]string{}, conf.Accessibility.Rules...)
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           &res,
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		ZeroFields:       true,
+	})
+	if err != nil {
+		return conf, err
+	}
+	if err := dec.Decode(overrides); err != nil {
+		return conf, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import "testing"
+
+func TestOverride(t *testing.T) {
+	base := CrawlerConfig{
+		Depth:   5,
+		Workers: 3,
+		Graph:   GraphConfig{Formats: []string{"dot", "json"}},
+		ExternalCheck: ExternalCheckConfig{
+			Workers:        5,
+			RequestsPerSec: 10,
+		},
+	}
+
+	conf, err := Override(base, map[string]interface{}{
+		"depth":          2,
+		"external_check": map[string]interface{}{"enabled": true},
+		"graph":          map[string]interface{}{"formats": []interface{}{"gexf"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Depth != 2 || conf.Workers != 3 {
+		t.Errorf("depth should be overridden and workers kept, got %d and %d", conf.Depth, conf.Workers)
+	}
+	if !conf.ExternalCheck.Enabled || conf.ExternalCheck.Workers != 5 || conf.ExternalCheck.RequestsPerSec != 10 {
+		t.Errorf("nested values should be merged, got %+v", conf.ExternalCheck)
+	}
+	if len(conf.Graph.Formats) != 1 || conf.Graph.Formats[0] != "gexf" {
+		t.Errorf("slices should be replaced, got %v", conf.Graph.Formats)
+	}
+	if len(base.Graph.Formats) != 2 || base.Graph.Formats[0] != "dot" {
+		t.Errorf("base should not change, got %v", base.Graph.Formats)
+	}
+
+	if _, err := Override(base, map[string]interface{}{"unknown": 1}); err == nil {
+		t.Error("unknown key should be an error")
+	}
+}
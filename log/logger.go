@@ -98,6 +98,11 @@ func SetLevel(level logrus.Level) {
 	logger.SetLevel(level)
 }
 
+// ParseLevel takes a string level and returns the log level constant.
+func ParseLevel(lvl string) (logrus.Level, error) {
+	return logrus.ParseLevel(lvl)
+}
+
 // GetLevel returns the standard logger level.
 func GetLevel() logrus.Level {
 	return logger.GetLevel()
@@ -1,68 +1,392 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/pflag"
 	"go-link-crawler/config"
 	"go-link-crawler/log"
 	"go-link-crawler/services"
-	"io/ioutil"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"crawl", "crawl seeds and write results", runCrawl},
+	{"check", "crawl seeds and fail if broken links are found", runCheck},
+	{"bench", "crawl seeds and report crawl speed", runBench},
+	{"analyze", "crawl seeds and write ranked tables of link analysis", runAnalyze},
+	{"diff", "compare two results written by crawl --format json", runDiff},
+	{"export", "export link graphs or pages of result file", runExport},
+	{"serve", "run http api", runServe},
+}
+
+// errCheckFailed is returned when command worked but found problems, it sets exit code only
+var errCheckFailed = errors.New("check failed")
+
+// interrupted is set to 1 when crawls were stopped by signal, program exits with code 130 then
+var interrupted int32
+
+// stopSignals stop crawls gracefully
+var stopSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// options are common flags of commands
+type options struct {
+	configPath       string
+	logLevel         string
+	output           string
+	format           string
+	formats          []string
+	seeds            string
+	seedsFormat      string
+	checkpoint       string
+	resume           string
+	progress         string
+	progressInterval time.Duration
+	checkpoints      []*services.Checkpoint // checkpoints of resume file
+}
+
 func main() {
-	// set log level
-	log.SetLevel(log.TraceLevel)
+	if len(os.Args) < 2 || os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "help" {
+		usage(os.Stdout)
+		return
+	}
+
+	for _, c := range commands {
+		if c.name != os.Args[1] {
+			continue
+		}
+
+		err := c.run(os.Args[2:])
+		switch {
+		case err == pflag.ErrHelp:
+		case err == errCheckFailed:
+			os.Exit(1)
+		case err != nil:
+			log.Errorf("%s: %v", c.name, err)
+			os.Exit(1)
+		}
+		if atomic.LoadInt32(&interrupted) == 1 {
+			os.Exit(130)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", os.Args[1])
+	usage(os.Stderr)
+	os.Exit(2)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s <command> [flags] [args]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(w, "\nRun '%s <command> --help' for flags of command\n", os.Args[0])
+}
+
+// newFlagSet returns flags of command with common flags, formats[0] is default format
+func newFlagSet(name, args string, o *options, formats ...string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags] %s\n\nFlags:\n%s", os.Args[0], name, args, flags.FlagUsages())
+	}
+	flags.SortFlags = false
+
+	flags.StringVar(&o.configPath, "config", "", "configuration file, default ./config/.go-link-crawler.yaml")
+	flags.StringVar(&o.logLevel, "log-level", "info", "log level: trace, debug, info, warn, error")
+	flags.StringVarP(&o.output, "output", "o", "-", "output file, - is stdout")
+	if len(formats) > 0 {
+		o.formats = formats
+		flags.StringVarP(&o.format, "format", "f", formats[0], "output format: "+strings.Join(formats, ", "))
+	}
+
+	return flags
+}
+
+// addCrawlFlags adds flags of commands which crawl seeds
+func addCrawlFlags(flags *pflag.FlagSet, o *options) {
+	flags.StringVar(&o.seeds, "seeds", "", "file with seeds, - is stdin")
+	flags.StringVar(&o.seedsFormat, "seeds-format", "", "format of seeds file: text, csv, jsonl, default by file extension")
+	flags.StringVar(&o.checkpoint, "checkpoint", "", "file where state of crawls stopped by signal is written for --resume")
+	flags.StringVar(&o.resume, "resume", "", "checkpoint file of stopped crawls to resume")
+	flags.StringVar(&o.progress, "progress", progressAuto, "progress view: auto (tty dashboard if stdout is a terminal, lines otherwise), tty, line, off")
+	flags.DurationVar(&o.progressInterval, "progress-interval", 10*time.Second, "interval of progress lines")
+	config.AddCrawlerFlags(flags)
+}
+
+// parseFlags parses args, sets log level and validates format and progress of commands which crawl
+func parseFlags(flags *pflag.FlagSet, o *options, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	level, err := log.ParseLevel(o.logLevel)
+	if err != nil {
+		return err
+	}
+	log.SetLevel(level)
+
+	if flags.Lookup("progress") != nil {
+		if err := checkProgress(o.progress); err != nil {
+			return err
+		}
+	}
+
+	if len(o.formats) == 0 {
+		return nil
+	}
+	for _, f := range o.formats {
+		if f == o.format {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown format: %s, use one of: %s", o.format, strings.Join(o.formats, ", "))
+}
+
+// readSeeds returns seeds of args, --seeds file and stdin if it is not a terminal and no seeds are given,
+// invalid lines are logged and skipped
+func readSeeds(args []string, o *options) ([]services.Seed, error) {
+	seeds, errs := services.ParseSeeds(strings.NewReader(strings.Join(args, "\n")), services.SeedsText)
+	for _, err := range errs {
+		log.Warnf("argument seeds: %v", err)
+	}
+
+	path := o.seeds
+	if path == "" && len(args) == 0 {
+		if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice == 0 && o.resume == "" {
+			path = "-"
+		}
+	}
+
+	if path != "" {
+		var r io.Reader = os.Stdin
+		if path != "-" {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("cannot open %s err: %v", path, err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		format := o.seedsFormat
+		if format == "" {
+			format = services.SeedsFormat(path)
+		}
+
+		fileSeeds, errs := services.ParseSeeds(r, format)
+		for _, err := range errs {
+			log.Warnf("%s: %v", path, err)
+		}
+
+		// seeds of file duplicating arguments are skipped
+		known := make(map[string]bool)
+		for _, s := range seeds {
+			known[s.Url] = true
+		}
+		for _, s := range fileSeeds {
+			if !known[s.Url] {
+				seeds = append(seeds, s)
+			}
+		}
+	}
 
-	conf := config.Init()
+	if len(seeds) == 0 && o.resume == "" {
+		return nil, errors.New("no valid seeds, pass urls as arguments, by --seeds file or stdin")
+	}
+
+	return seeds, nil
+}
+
+// crawl starts checkpoints and seeds and calls fn with result of every crawl in order of start,
+// checkpoints of crawls stopped by signal are written to --checkpoint file
+func crawl(conf *config.Configuration, o *options, seeds []services.Seed, fn func(res services.CrawlerResult) error) error {
 	crawler := services.NewCrawlerService(conf.CrawlerConfig)
+	defer crawler.Close()
 
-	if len(os.Args) < 2 {
-		log.Fatalf("use filepath as first argument")
+	crawlerProcesses := make([]*services.CrawlerProcess, 0, len(o.checkpoints)+len(seeds))
+	for _, c := range o.checkpoints {
+		p, err := crawler.StartCheckpoint(c)
+		if err != nil {
+			log.Errorf("crawler.StartCheckpoint %s err: %v", c.Seed.Url, err)
+			continue
+		}
+
+		crawlerProcesses = append(crawlerProcesses, p)
 	}
+	for _, seed := range seeds {
+		p, err := crawler.StartSeed(seed)
+		if err != nil {
+			log.Errorf("crawler.StartSeed %s err: %v", seed.Url, err)
+			continue
+		}
 
-	arg := os.Args[1]
-	f, err := os.Open(arg)
+		crawlerProcesses = append(crawlerProcesses, p)
+	}
+
+	stop := watchSignals(crawlerProcesses)
+	defer stop()
+
+	view, err := newProgress(o, crawlerProcesses)
 	if err != nil {
-		log.Fatalf("cannot open %s err: %v", arg, err)
+		return err
 	}
+	defer view.stop()
 
-	data, err := ioutil.ReadAll(f)
+	for _, p := range crawlerProcesses {
+		res := p.GetResult()
+		if err := view.run(func() error { return fn(res) }); err != nil {
+			return err
+		}
+	}
+
+	if o.checkpoint != "" && atomic.LoadInt32(&interrupted) == 1 {
+		return writeCheckpoints(o.checkpoint, crawlerProcesses)
+	}
+
+	return nil
+}
+
+// readCheckpoints reads jsonl file written by writeCheckpoints
+func readCheckpoints(path string) ([]*services.Checkpoint, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		log.Fatalf("cannot read %s err: %v", arg, err)
+		return nil, fmt.Errorf("cannot open %s err: %v", path, err)
 	}
+	defer f.Close()
 
-	links := strings.Split(string(data), "\n")
-	crawlerProcesses := make([]*services.CrawlerProcess, 0)
-	for _, link := range links {
-		p, err := crawler.Start(link)
+	res := make([]*services.Checkpoint, 0)
+	dec := json.NewDecoder(f)
+	for {
+		c := &services.Checkpoint{}
+		err := dec.Decode(c)
+		if err == io.EOF {
+			return res, nil
+		}
 		if err != nil {
-			log.Errorf("crawler.Start err: %v", err)
+			return nil, fmt.Errorf("invalid checkpoint %s err: %v", path, err)
 		}
+		res = append(res, c)
+	}
+}
 
-		crawlerProcesses = append(crawlerProcesses, p)
+// writeCheckpoints writes checkpoints of interrupted crawls as jsonl
+func writeCheckpoints(path string, crawlerProcesses []*services.CrawlerProcess) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
 
-	// get results
-	var req float32
+	enc := json.NewEncoder(f)
 	count := 0
-	req = 0
 	for _, p := range crawlerProcesses {
-		res := p.GetResult()
-		//j, err := json.Marshal(res)
-		//if err != nil {
-		//	log.Errorf("json.Marshal(res) err: %v", err)
-		//	continue
-		//}
-		log.Infof("Domain: %s, Links count: %d, External links count: %d, req/sec: %.2f", res.Domain, res.InnerLinksCount, res.ExternalLinksCount, res.RequestsPerSec)
+		if !p.Interrupted() {
+			continue
+		}
+		c, err := p.Checkpoint()
+		if err != nil {
+			log.Errorf("checkpoint of %s err: %v", p.Stats().Domain, err)
+			continue
+		}
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
 		count++
-		req = req + res.RequestsPerSec
 	}
 
-	if count > 0 {
-		req = req / float32(count)
+	log.Infof("%d interrupted crawls are written to %s, continue them by --resume %s", count, path, path)
+	return nil
+}
+
+// watchSignals logs stats of crawls on stats signals and stops them gracefully on the first stop signal,
+// the second stop signal kills program
+func watchSignals(crawlerProcesses []*services.CrawlerProcess) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, append(append([]os.Signal{}, stopSignals...), statsSignals...)...)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case sig := <-signals:
+				if sig == os.Interrupt || sig == syscall.SIGTERM {
+					log.Warnf("%v: stopping crawls, signal again to exit immediately", sig)
+					atomic.StoreInt32(&interrupted, 1)
+					signal.Reset(stopSignals...)
+					for _, p := range crawlerProcesses {
+						p.Stop()
+					}
+					continue
+				}
+
+				for _, p := range crawlerProcesses {
+					s := p.Stats()
+					log.Infof("%s: %s, fetched %d, queued %d, in flight %d, errors %d, workers %d, %.2f req/s, elapsed %v",
+						s.Domain, s.State, s.Fetched, s.Queued, s.InFlight, s.Errors, s.Workers, s.RequestsPerSec, s.Elapsed.Round(time.Second))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// crawlCommand parses flags, loads config and seeds of commands which crawl
+func crawlCommand(flags *pflag.FlagSet, o *options, args []string) (*config.Configuration, []services.Seed, error) {
+	if err := parseFlags(flags, o, args); err != nil {
+		return nil, nil, err
+	}
+
+	conf, err := config.Load(o.configPath, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seeds, err := readSeeds(flags.Args(), o)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if o.resume != "" {
+		if o.checkpoints, err = readCheckpoints(o.resume); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	log.Infof("requests/sec: %.2f", req)
+	return conf, seeds, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
 
-	crawler.Close()
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// openOutput returns stdout for empty path or -
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
 }
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go-link-crawler/log"
+	"go-link-crawler/services"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// modes of --progress
+const (
+	progressAuto = "auto"
+	progressTty  = "tty"
+	progressLine = "line"
+	progressOff  = "off"
+
+	dashboardRefresh  = 500 * time.Millisecond
+	dashboardLogLines = 5
+)
+
+// reAnsi matches color escape codes of log lines
+var reAnsi = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// isTerminal returns true if file is a character device like terminal
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalSize returns function reading width and height of terminal of file, they are 0 if unknown
+func terminalSize(f *os.File) func() (int, int) {
+	return func() (int, int) {
+		width, height, err := terminal.GetSize(int(f.Fd()))
+		if err != nil {
+			return 0, 0
+		}
+		return width, height
+	}
+}
+
+// checkProgress validates --progress before crawls are started
+func checkProgress(mode string) error {
+	switch mode {
+	case progressAuto, progressTty, progressLine, progressOff:
+		return nil
+	}
+	return fmt.Errorf("unknown progress: %s, use auto, tty, line or off", mode)
+}
+
+// progress shows progress of crawls until it is stopped, run writes results of crawls
+// which would break dashboard otherwise
+type progress interface {
+	run(fn func() error) error
+	stop()
+}
+
+// newProgress starts dashboard if stdout and stderr are terminals or periodic progress lines otherwise,
+// mode is validated by parseFlags
+func newProgress(o *options, crawlerProcesses []*services.CrawlerProcess) (progress, error) {
+	mode := o.progress
+	if mode == progressAuto {
+		mode = progressLine
+		if isTerminal(os.Stdout) && isTerminal(os.Stderr) {
+			mode = progressTty
+		}
+	}
+
+	switch mode {
+	case progressTty:
+		return newDashboard(os.Stderr, terminalSize(os.Stderr), crawlerProcesses), nil
+	case progressLine:
+		return newProgressLines(o.progressInterval, crawlerProcesses), nil
+	case progressOff:
+		return noProgress{}, nil
+	}
+	return nil, fmt.Errorf("unknown progress: %s, use auto, tty, line or off", o.progress)
+}
+
+type noProgress struct{}
+
+func (noProgress) run(fn func() error) error { return fn() }
+
+func (noProgress) stop() {}
+
+// progressLines logs one line with progress of all crawls periodically
+type progressLines struct {
+	done chan struct{}
+}
+
+func newProgressLines(interval time.Duration, crawlerProcesses []*services.CrawlerProcess) *progressLines {
+	l := &progressLines{done: make(chan struct{})}
+	if interval <= 0 {
+		return l
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		fetched := 0
+		for {
+			select {
+			case <-l.done:
+				return
+			case <-ticker.C:
+			}
+
+			running, queued, errors, total := 0, 0, 0, 0
+			for _, p := range crawlerProcesses {
+				s := p.Stats()
+				if s.State != services.StateCompleted && s.State != services.StateStopped {
+					running++
+				}
+				queued += s.Queued
+				errors += s.Errors
+				total += s.Fetched
+			}
+			log.Infof("progress: %d of %d crawls running, fetched %d, queued %d, errors %d, %.2f req/s",
+				running, len(crawlerProcesses), total, queued, errors, float64(total-fetched)/interval.Seconds())
+			fetched = total
+		}
+	}()
+
+	return l
+}
+
+func (l *progressLines) run(fn func() error) error { return fn() }
+
+func (l *progressLines) stop() {
+	close(l.done)
+}
+
+// dashboardRow is previous state of crawl for current rate
+type dashboardRow struct {
+	fetched int
+	time    time.Time
+	rate    float64
+}
+
+// dashboard redraws table of crawls in terminal, log lines are shown under it instead of scrolling,
+// rows of finished crawls are hidden first if table does not fit in terminal
+type dashboard struct {
+	w         io.Writer
+	size      func() (int, int) // width and height of terminal, 0 if unknown
+	processes []*services.CrawlerProcess
+	rows      []dashboardRow
+	logs      []string
+	partial   []byte // log output without new line yet
+	lines     int    // count of lines drawn last time
+	suspended bool   // results are written, dashboard is not drawn
+	done      chan struct{}
+	stopped   chan struct{}
+	mux       sync.Mutex
+}
+
+func newDashboard(w io.Writer, size func() (int, int), crawlerProcesses []*services.CrawlerProcess) *dashboard {
+	d := &dashboard{
+		w:         w,
+		size:      size,
+		processes: crawlerProcesses,
+		rows:      make([]dashboardRow, len(crawlerProcesses)),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	log.SetOutput(d)
+
+	go func() {
+		defer close(d.stopped)
+
+		ticker := time.NewTicker(dashboardRefresh)
+		defer ticker.Stop()
+		for {
+			d.refresh()
+
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return d
+}
+
+// Write keeps last log lines to show them under table
+func (d *dashboard) Write(b []byte) (int, error) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
+	d.partial = append(d.partial, b...)
+	for {
+		i := bytes.IndexByte(d.partial, '\n')
+		if i < 0 {
+			break
+		}
+		d.logs = append(d.logs, reAnsi.ReplaceAllString(string(d.partial[:i]), ""))
+		d.partial = d.partial[i+1:]
+	}
+	if len(d.logs) > dashboardLogLines {
+		d.logs = d.logs[len(d.logs)-dashboardLogLines:]
+	}
+
+	return len(b), nil
+}
+
+// clear removes lines drawn last time, it has to be called under d.mux lock
+func (d *dashboard) clear() {
+	if d.lines > 0 {
+		fmt.Fprintf(d.w, "\x1b[%dA\x1b[J", d.lines)
+		d.lines = 0
+	}
+}
+
+// collect returns stats of crawls, it is called without d.mux lock because stats of crawl
+// can log and log is written to dashboard
+func (d *dashboard) collect() []services.CrawlerStats {
+	stats := make([]services.CrawlerStats, len(d.processes))
+	for i, p := range d.processes {
+		stats[i] = p.Stats()
+	}
+	return stats
+}
+
+// refresh collects stats and redraws table
+func (d *dashboard) refresh() {
+	stats := d.collect()
+
+	d.mux.Lock()
+	d.draw(stats)
+	d.mux.Unlock()
+}
+
+// draw redraws table of stats, it has to be called under d.mux lock
+func (d *dashboard) draw(stats []services.CrawlerStats) {
+	if d.suspended {
+		return
+	}
+
+	now := time.Now()
+	rows := make([]string, 0, len(stats))
+	running, finished := make([]string, 0, len(stats)), make([]string, 0)
+	for i, s := range stats {
+		row := &d.rows[i]
+		if !row.time.IsZero() {
+			if dt := now.Sub(row.time).Seconds(); dt > 0 {
+				// smooth rate of the last refreshes
+				row.rate = 0.7*row.rate + 0.3*float64(s.Fetched-row.fetched)/dt
+			}
+		}
+		row.fetched, row.time = s.Fetched, now
+
+		eta := "-"
+		if s.State == services.StateRunning && row.rate > 0 {
+			eta = (time.Duration(float64(s.Queued+s.InFlight)/row.rate) * time.Second).Round(time.Second).String()
+		}
+		done := s.State == services.StateCompleted || s.State == services.StateStopped
+		if done {
+			row.rate = 0
+		}
+
+		line := fmt.Sprintf("%-32s %-10s %8d %8d %6d %8.2f %8s", truncate(s.Domain, 32), s.State, s.Fetched, s.Queued, s.Errors, row.rate, eta)
+		rows = append(rows, line)
+		if done {
+			finished = append(finished, line)
+		} else {
+			running = append(running, line)
+		}
+	}
+
+	width, height := d.size()
+	logs := d.logs
+	if height > 0 {
+		// table with logs fits above the cursor line, header and one row are always drawn
+		free := height - 2
+		if free < 1 {
+			free = 1
+		}
+		if len(logs) >= free {
+			logs = logs[len(logs)-(free-1):]
+		}
+		free -= len(logs)
+		if len(rows) > free {
+			more := fmt.Sprintf("... %d more crawls, %d running, %d finished", len(rows)-free+1, len(running), len(finished))
+			rows = append(append(running, finished...)[:free-1], more)
+		}
+	}
+
+	maxWidth := 120
+	if width > 0 && width < maxWidth {
+		maxWidth = width
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, truncate(fmt.Sprintf("%-32s %-10s %8s %8s %6s %8s %8s", "DOMAIN", "STATE", "DONE", "QUEUED", "ERRORS", "REQ/S", "ETA"), maxWidth))
+	for _, l := range rows {
+		fmt.Fprintln(buf, truncate(l, maxWidth))
+	}
+	for _, l := range logs {
+		fmt.Fprintln(buf, truncate(l, maxWidth))
+	}
+
+	d.clear()
+	d.lines = strings.Count(buf.String(), "\n")
+	d.w.Write(buf.Bytes())
+}
+
+// run writes above dashboard, fn can log
+func (d *dashboard) run(fn func() error) error {
+	d.mux.Lock()
+	d.clear()
+	d.suspended = true
+	d.mux.Unlock()
+
+	err := fn()
+
+	stats := d.collect()
+	d.mux.Lock()
+	d.suspended = false
+	d.draw(stats)
+	d.mux.Unlock()
+
+	return err
+}
+
+// stop draws final state without log lines and restores log output
+func (d *dashboard) stop() {
+	close(d.done)
+	<-d.stopped
+
+	stats := d.collect()
+	d.mux.Lock()
+	d.logs = nil
+	d.draw(stats)
+	d.mux.Unlock()
+
+	log.SetOutput(os.Stderr)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n || n <= 3 {
+		return s
+	}
+	return s[:n-3] + "..."
+}
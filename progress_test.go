@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go-link-crawler/services"
+	"strings"
+	"testing"
+)
+
+func TestDashboardFitsTerminal(t *testing.T) {
+	stats := make([]services.CrawlerStats, 30)
+	for i := range stats {
+		stats[i] = services.CrawlerStats{Domain: fmt.Sprintf("site%d.com", i), State: services.StateCompleted}
+	}
+	stats[20].State = services.StateRunning
+
+	buf := &bytes.Buffer{}
+	d := &dashboard{
+		w:    buf,
+		size: func() (int, int) { return 60, 10 },
+		rows: make([]dashboardRow, len(stats)),
+		logs: []string{"first log line", "second log line"},
+	}
+	d.draw(stats)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 9 {
+		t.Fatalf("dashboard should fit in 9 lines above cursor, got %d:\n%s", len(lines), buf.String())
+	}
+	for _, l := range lines {
+		if len(l) > 60 {
+			t.Errorf("line should fit in terminal width: %q", l)
+		}
+	}
+	if !strings.HasPrefix(lines[1], "site20.com") {
+		t.Errorf("running crawl should be shown first, got %q", lines[1])
+	}
+	if lines[6] != "... 25 more crawls, 1 running, 29 finished" || lines[8] != "second log line" {
+		t.Errorf("unexpected summary or logs:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	d.draw(stats)
+	if !strings.HasPrefix(buf.String(), "\x1b[9A\x1b[J") {
+		t.Errorf("dashboard should be redrawn over 9 lines, got %q", buf.String()[:10])
+	}
+}
+
+func TestDashboardUnknownSize(t *testing.T) {
+	stats := []services.CrawlerStats{{Domain: "site.com", State: services.StateRunning}, {Domain: "other.com", State: services.StateQueued}}
+
+	buf := &bytes.Buffer{}
+	d := &dashboard{
+		w:    buf,
+		size: func() (int, int) { return 0, 0 },
+		rows: make([]dashboardRow, len(stats)),
+	}
+	d.draw(stats)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "site.com") || !strings.HasPrefix(lines[2], "other.com") {
+		t.Errorf("all crawls should be shown in order, got:\n%s", buf.String())
+	}
+}
+
+func TestParseFlagsProgress(t *testing.T) {
+	o := &options{}
+	flags := newFlagSet("crawl", "[seeds]", o, "text")
+	addCrawlFlags(flags, o)
+	if err := parseFlags(flags, o, []string{"--progress", "fancy"}); err == nil {
+		t.Error("unknown progress should be an error before crawls are started")
+	}
+
+	o = &options{}
+	flags = newFlagSet("crawl", "[seeds]", o, "text")
+	addCrawlFlags(flags, o)
+	if err := parseFlags(flags, o, []string{"--progress", "line"}); err != nil {
+		t.Errorf("line progress should be valid, got %v", err)
+	}
+
+	// commands which do not crawl have no progress
+	o = &options{}
+	if err := parseFlags(newFlagSet("export", "", o, "json"), o, nil); err != nil {
+		t.Errorf("command without progress flag should be valid, got %v", err)
+	}
+}
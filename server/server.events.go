@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-link-crawler/log"
+	"go-link-crawler/services"
+	"golang.org/x/net/websocket"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sseKeepAlive is interval of comments sent to idle event stream so proxies do not close it
+const sseKeepAlive = 15 * time.Second
+
+// subscribe merges live events of all crawls of job, channel is closed when all crawls are finished
+// and done events are sent or when ctx is done
+func (s *Server) subscribe(ctx context.Context, id string) (<-chan services.CrawlerEvent, int, error) {
+	s.mux.RLock()
+	j, ok := s.jobs[id]
+	var processes []*services.CrawlerProcess
+	state := ""
+	if ok {
+		processes = j.processes
+		state = j.State
+	}
+	s.mux.RUnlock()
+
+	if !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("job %s is not found", id)
+	}
+	// finished jobs, jobs loaded after restart and failed jobs have no crawls
+	if len(processes) == 0 {
+		return nil, http.StatusConflict, fmt.Errorf("job %s is %s, events are not available", id, state)
+	}
+
+	out := make(chan services.CrawlerEvent)
+	wg := sync.WaitGroup{}
+	for _, p := range processes {
+		events, unsubscribe := p.Subscribe()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer unsubscribe()
+			for e := range events {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out, http.StatusOK, nil
+}
+
+// streamEvents sends events of job as server-sent events, event name is type of event and data is json
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, id string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	events, status, err := s.subscribe(ctx, id)
+	if err != nil {
+		writeError(w, status, "%v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			b, err := json.Marshal(e)
+			if err != nil {
+				log.WithTrace("server", "streamEvents").Errorf("json.Marshal err: %v", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, b); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// websocketEvents sends events of job as json text messages over websocket, connection is closed
+// after the last event, messages of client are ignored
+func (s *Server) websocketEvents(w http.ResponseWriter, r *http.Request, id string) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	events, status, err := s.subscribe(ctx, id)
+	if err != nil {
+		writeError(w, status, "%v", err)
+		return
+	}
+
+	// origin is not checked, api is meant for internal dashboards
+	ws := websocket.Server{Handler: func(conn *websocket.Conn) {
+		// context of hijacked request is not canceled when client goes away, reader notices it
+		go func() {
+			io.Copy(ioutil.Discard, conn)
+			cancel()
+		}()
+
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(conn, e); err != nil {
+					log.WithTrace("server", "websocketEvents").Debugf("job %s websocket.JSON.Send err: %v", id, err)
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}}
+	ws.ServeHTTP(w, r)
+}
@@ -0,0 +1,423 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"go-link-crawler/services"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRequestBody limits size of submitted job
+const maxRequestBody = 1 << 20
+
+// Server is http api to submit, monitor and cancel crawl jobs:
+//
+//	POST /jobs                   submits job {"seeds": [{"url": "example.com", "depth": 2}], "config": {"workers": 5}}
+//	GET  /jobs                   lists jobs
+//	GET  /jobs/{id}              returns job with stats of its crawls
+//	POST /jobs/{id}/cancel       stops crawls of job, partial results are kept
+//	GET  /jobs/{id}/result       returns results of finished job, ?format=csv returns pages as csv
+//	GET  /jobs/{id}/events       streams page, error, progress and done events of running job as server-sent events
+//	GET  /jobs/{id}/ws           streams the same events over websocket
+//
+// Jobs are persisted in data dir, unfinished jobs are started again from scratch when server is restarted.
+type Server struct {
+	conf    config.CrawlerConfig
+	crawler *services.CrawlerService
+	store   *jobStore
+	jobs    map[string]*job
+	order   []*job
+	mux     sync.RWMutex
+	saves   sync.Mutex
+}
+
+// jobRequest is body of submitted job, config has keys of configuration file overriding config of server
+type jobRequest struct {
+	Seeds  []services.Seed        `json:"seeds"`
+	Config map[string]interface{} `json:"config"`
+}
+
+// New loads jobs of dir and starts unfinished ones, crawls of all jobs share scheduler of crawler
+func New(conf config.CrawlerConfig, crawler *services.CrawlerService, dir string) (*Server, error) {
+	store, err := newJobStore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := store.load()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		conf:    conf,
+		crawler: crawler,
+		store:   store,
+		jobs:    make(map[string]*job),
+	}
+
+	for _, j := range jobs {
+		s.jobs[j.Id] = j
+		s.order = append(s.order, j)
+	}
+	for _, j := range jobs {
+		if !j.finished() {
+			log.WithTrace("server", "New").Infof("job %s is started again after restart", j.Id)
+			j.State = JobQueued
+			j.StartedAt = nil
+			s.start(j)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "jobs" || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		s.listJobs(w, r)
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		s.createJob(w, r)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		s.getJob(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "cancel" && r.Method == http.MethodPost:
+		s.cancelJob(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "result" && r.Method == http.MethodGet:
+		s.getResult(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "events" && r.Method == http.MethodGet:
+		s.streamEvents(w, r, parts[1])
+	case len(parts) == 3 && parts[2] == "ws" && r.Method == http.MethodGet:
+		s.websocketEvents(w, r, parts[1])
+	case len(parts) <= 2 || parts[2] == "cancel" || parts[2] == "result" || parts[2] == "events" || parts[2] == "ws":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.WithTrace("server", "writeJSON").Errorf("json.Encode err: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// status returns copy of job with stats, it has to be called under s.mux lock
+func (s *Server) status(j *job) jobStatus {
+	cp := *j
+	res := jobStatus{job: &cp, Stats: j.stats}
+	for _, p := range j.processes {
+		res.Stats = append(res.Stats, p.Stats())
+	}
+	return res
+}
+
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	s.mux.RLock()
+	res := make([]jobStatus, 0, len(s.order))
+	for _, j := range s.order {
+		res = append(res, s.status(j))
+	}
+	s.mux.RUnlock()
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) createJob(w http.ResponseWriter, r *http.Request) {
+	req := jobRequest{}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid job: %v", err)
+		return
+	}
+
+	// seeds are validated like seed files, errors have index of seed starting from 1
+	lines := &bytes.Buffer{}
+	enc := json.NewEncoder(lines)
+	for _, seed := range req.Seeds {
+		enc.Encode(seed)
+	}
+	seeds, errs := services.ParseSeeds(lines, services.SeedsJsonl)
+	if len(errs) > 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, err := range errs {
+			msgs = append(msgs, strings.Replace(err.Error(), "line", "seed", 1))
+		}
+		writeError(w, http.StatusBadRequest, "invalid seeds: %s", strings.Join(msgs, "; "))
+		return
+	}
+	if len(seeds) == 0 {
+		writeError(w, http.StatusBadRequest, "seeds are required")
+		return
+	}
+
+	if _, err := s.jobConfig(req.Config); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid config: %v", err)
+		return
+	}
+
+	id, err := newJobId()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "job id is not generated: %v", err)
+		return
+	}
+
+	j := &job{
+		Id:        id,
+		State:     JobQueued,
+		Seeds:     seeds,
+		Config:    req.Config,
+		CreatedAt: time.Now(),
+	}
+
+	s.mux.Lock()
+	s.jobs[j.Id] = j
+	s.order = append(s.order, j)
+	s.mux.Unlock()
+
+	log.WithTrace("server", "createJob").Infof("job %s is submitted with %d seeds", j.Id, len(seeds))
+	s.start(j)
+
+	s.mux.RLock()
+	res := s.status(j)
+	s.mux.RUnlock()
+
+	writeJSON(w, http.StatusCreated, res)
+}
+
+// jobConfig returns config of server with overrides of job
+func (s *Server) jobConfig(overrides map[string]interface{}) (config.CrawlerConfig, error) {
+	if err := checkJobConfig(overrides); err != nil {
+		return s.conf, err
+	}
+	return config.Override(s.conf, overrides)
+}
+
+// start crawls seeds of job and stores results when all crawls are finished,
+// crawls are started and job is saved outside of s.mux lock
+func (s *Server) start(j *job) {
+	s.mux.RLock()
+	overrides, seeds := j.Config, j.Seeds
+	s.mux.RUnlock()
+
+	// jobs loaded from data dir are checked like submitted ones
+	conf, err := s.jobConfig(overrides)
+	if err != nil {
+		s.fail(j, err)
+		return
+	}
+	crawler := s.crawler.WithConfig(conf)
+
+	processes := make([]*services.CrawlerProcess, 0, len(seeds))
+	for _, seed := range seeds {
+		p, err := crawler.StartSeed(seed)
+		if err != nil {
+			for _, p := range processes {
+				p.Stop()
+			}
+			crawler.Close()
+			s.fail(j, err)
+			return
+		}
+		processes = append(processes, p)
+	}
+
+	s.mux.Lock()
+	now := time.Now()
+	j.processes = processes
+	j.State = JobRunning
+	j.StartedAt = &now
+	canceled := j.canceled
+	s.mux.Unlock()
+
+	// job canceled while its crawls were starting had no processes to stop
+	if canceled {
+		for _, p := range processes {
+			p.Stop()
+		}
+	}
+	s.save(j)
+
+	go s.wait(j, crawler, processes)
+}
+
+// fail marks job which cannot be started as failed
+func (s *Server) fail(j *job, err error) {
+	log.WithTrace("server", "start").Errorf("job %s err: %v", j.Id, err)
+
+	s.mux.Lock()
+	now := time.Now()
+	j.State = JobFailed
+	j.Error = err.Error()
+	j.FinishedAt = &now
+	s.mux.Unlock()
+
+	s.save(j)
+}
+
+// wait stores results of finished job and closes its crawler, job keeps only final stats of its crawls
+func (s *Server) wait(j *job, crawler *services.CrawlerService, processes []*services.CrawlerProcess) {
+	results := make([]services.CrawlerResult, 0, len(processes))
+	stats := make([]services.CrawlerStats, 0, len(processes))
+	for _, p := range processes {
+		results = append(results, p.GetResult())
+		stats = append(stats, p.Stats())
+	}
+	crawler.Close()
+
+	err := s.store.saveResults(j.Id, results)
+
+	s.mux.Lock()
+	now := time.Now()
+	j.processes = nil
+	j.stats = stats
+	j.FinishedAt = &now
+	switch {
+	case err != nil:
+		j.State = JobFailed
+		j.Error = fmt.Sprintf("results are not saved: %v", err)
+	case j.canceled:
+		j.State = JobCanceled
+	default:
+		j.State = JobCompleted
+	}
+	state := j.State
+	s.mux.Unlock()
+
+	s.save(j)
+
+	log.WithTrace("server", "wait").Infof("job %s is %s", j.Id, state)
+}
+
+// save persists snapshot of job, it must not be called under s.mux lock.
+// Saves are serialized and each one snapshots job when it writes, so the last save has the latest state of job.
+func (s *Server) save(j *job) {
+	s.saves.Lock()
+	defer s.saves.Unlock()
+
+	s.mux.RLock()
+	snapshot := *j
+	s.mux.RUnlock()
+
+	if err := s.store.save(&snapshot); err != nil {
+		log.WithTrace("server", "save").Errorf("job %s is not saved err: %v", j.Id, err)
+	}
+}
+
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request, id string) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "job %s is not found", id)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.status(j))
+}
+
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request, id string) {
+	s.mux.Lock()
+	j, ok := s.jobs[id]
+	if !ok {
+		s.mux.Unlock()
+		writeError(w, http.StatusNotFound, "job %s is not found", id)
+		return
+	}
+	if j.finished() {
+		s.mux.Unlock()
+		writeError(w, http.StatusConflict, "job %s is %s", id, j.State)
+		return
+	}
+
+	j.canceled = true
+	processes := j.processes
+	s.mux.Unlock()
+
+	log.WithTrace("server", "cancelJob").Infof("job %s is canceled", id)
+	for _, p := range processes {
+		p.Stop()
+	}
+
+	s.mux.RLock()
+	res := s.status(j)
+	s.mux.RUnlock()
+
+	writeJSON(w, http.StatusAccepted, res)
+}
+
+func (s *Server) getResult(w http.ResponseWriter, r *http.Request, id string) {
+	s.mux.RLock()
+	j, ok := s.jobs[id]
+	state := ""
+	if ok {
+		state = j.State
+	}
+	s.mux.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "job %s is not found", id)
+		return
+	}
+	if state != JobCompleted && state != JobCanceled {
+		writeError(w, http.StatusConflict, "job %s is %s, results are not ready", id, state)
+		return
+	}
+
+	results, err := s.store.loadResults(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "results of job %s are not loaded: %v", id, err)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		writeJSON(w, http.StatusOK, results)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		writePagesCsv(w, results)
+	default:
+		writeError(w, http.StatusBadRequest, "unknown format %s, use json or csv", r.URL.Query().Get("format"))
+	}
+}
+
+// writePagesCsv writes pages of all results sorted by url
+func writePagesCsv(w http.ResponseWriter, results []services.CrawlerResult) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"domain", "url", "status_code", "title", "content_hash"})
+	for _, res := range results {
+		urls := make([]string, 0, len(res.Pages))
+		for l := range res.Pages {
+			urls = append(urls, l)
+		}
+		sort.Strings(urls)
+
+		for _, l := range urls {
+			p := res.Pages[l]
+			cw.Write([]string{res.Domain, l, strconv.Itoa(p.StatusCode), p.Title, p.ContentHash})
+		}
+	}
+	cw.Flush()
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-link-crawler/log"
+	"go-link-crawler/services"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// states of job
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobCanceled  = "canceled"
+	JobFailed    = "failed"
+)
+
+// job is crawl of seeds with config overrides, it is persisted as <id>.json in data dir
+// and its results as <id>.result.json
+type job struct {
+	Id         string                 `json:"id"`
+	State      string                 `json:"state"`
+	Seeds      []services.Seed        `json:"seeds"`
+	Config     map[string]interface{} `json:"config,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+
+	processes []*services.CrawlerProcess // crawls of running job
+	stats     []services.CrawlerStats    // final stats of finished job, crawls are released
+	canceled  bool
+}
+
+// jobStatus is job with live stats of its crawls
+type jobStatus struct {
+	*job
+	Stats []services.CrawlerStats `json:"stats,omitempty"`
+}
+
+// jobConfigKeys are keys of configuration file which job may override, sections with listed keys
+// are overridden only by them, so job cannot write to paths of server like graph.dir or storage.pages_dir
+// and scheduler limits shared by all jobs stay the same
+var jobConfigKeys = map[string][]string{
+	"depth":                 nil,
+	"workers":               nil,
+	"use_regex_for_parsing": nil,
+	"robots_policy":         nil,
+	"stop_grace_sec":        nil,
+	"check_fragments":       nil,
+	"sitemap":               nil,
+	"graph":                 {"enabled", "formats"},
+	"analysis":              nil,
+	"seo":                   nil,
+	"canonical":             nil,
+	"duplicates":            nil,
+	"traps":                 nil,
+	"external_check":        nil,
+	"security":              nil,
+	"accessibility":         nil,
+	"frontier":              nil,
+	"storage":               {"visited", "false_positive_rate", "expected_urls"},
+	"adaptive":              nil,
+	"extract":               nil,
+}
+
+// checkJobConfig returns error for the first key of overrides which job may not override
+func checkJobConfig(overrides map[string]interface{}) error {
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		allowed, ok := jobConfigKeys[key]
+		if !ok {
+			return fmt.Errorf("%s cannot be overridden by job", key)
+		}
+		// values which are not sections are rejected by config.Override
+		section, ok := overrides[key].(map[string]interface{})
+		if allowed == nil || !ok {
+			continue
+		}
+
+		subkeys := make([]string, 0, len(section))
+		for subkey := range section {
+			subkeys = append(subkeys, subkey)
+		}
+		sort.Strings(subkeys)
+		for _, subkey := range subkeys {
+			found := false
+			for _, a := range allowed {
+				found = found || a == subkey
+			}
+			if !found {
+				return fmt.Errorf("%s.%s cannot be overridden by job", key, subkey)
+			}
+		}
+	}
+
+	return nil
+}
+
+func newJobId() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x-%s", time.Now().UnixNano(), hex.EncodeToString(b)), nil
+}
+
+func (j *job) finished() bool {
+	return j.State == JobCompleted || j.State == JobCanceled || j.State == JobFailed
+}
+
+// jobStore keeps jobs and results in dir
+type jobStore struct {
+	dir string
+}
+
+func newJobStore(dir string) (*jobStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &jobStore{dir: dir}, nil
+}
+
+func (s *jobStore) jobPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *jobStore) resultPath(id string) string {
+	return filepath.Join(s.dir, id+".result.json")
+}
+
+// writeFile replaces file by temporary file so it is never partially written
+func writeFile(path string, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *jobStore) save(j *job) error {
+	return writeFile(s.jobPath(j.Id), j)
+}
+
+func (s *jobStore) saveResults(id string, results []services.CrawlerResult) error {
+	return writeFile(s.resultPath(id), results)
+}
+
+func (s *jobStore) loadResults(id string) ([]services.CrawlerResult, error) {
+	b, err := ioutil.ReadFile(s.resultPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]services.CrawlerResult, 0)
+	if err := json.Unmarshal(b, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// load returns all jobs of dir ordered by creation, invalid files are logged and skipped
+func (s *jobStore) load() ([]*job, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*job, 0, len(paths))
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".result.json") {
+			continue
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		j := &job{}
+		if err := json.Unmarshal(b, j); err != nil || j.Id == "" {
+			log.WithTrace("server", "jobStore", "load").Errorf("invalid job file %s err: %v", path, err)
+			continue
+		}
+		jobs = append(jobs, j)
+	}
+
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt.Before(jobs[k].CreatedAt) })
+
+	return jobs, nil
+}
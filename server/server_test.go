@@ -0,0 +1,235 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-link-crawler/config"
+	"go-link-crawler/services"
+	"golang.org/x/net/websocket"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, dir string) (*Server, *httptest.Server) {
+	conf := config.CrawlerConfig{Depth: 3, Workers: 2, StopGraceSec: 1}
+	s, err := New(conf, services.NewCrawlerService(conf), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, httptest.NewServer(s)
+}
+
+func doJSON(t *testing.T, method, url, body string, v interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %v", method, url, err)
+		}
+	}
+	return res.StatusCode
+}
+
+func waitJob(t *testing.T, api, id string) map[string]interface{} {
+	for i := 0; i < 200; i++ {
+		job := map[string]interface{}{}
+		doJSON(t, http.MethodGet, api+"/jobs/"+id, "", &job)
+		if job["finished_at"] != nil {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s is not finished", id)
+	return nil
+}
+
+func TestServerJobs(t *testing.T) {
+	slow := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-slow:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		fmt.Fprint(w, `<html><head><title>page</title></head><body><a href="/a">a</a></body></html>`)
+	}))
+	defer site.Close()
+	defer close(slow)
+
+	dir, err := ioutil.TempDir("", "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, api := newTestServer(t, dir)
+	defer api.Close()
+
+	job := map[string]interface{}{}
+	if status := doJSON(t, http.MethodPost, api.URL+"/jobs", `{"seeds": [{"url": "ftp://example.com"}]}`, &job); status != http.StatusBadRequest {
+		t.Errorf("invalid seed should be rejected, got %d %v", status, job)
+	}
+	if status := doJSON(t, http.MethodPost, api.URL+"/jobs", `{"seeds": [{"url": "example.com"}], "config": {"unknown": 1}}`, &job); status != http.StatusBadRequest {
+		t.Errorf("invalid config should be rejected, got %d %v", status, job)
+	}
+	for _, conf := range []string{`{"graph": {"dir": "/tmp"}}`, `{"storage": {"pages_dir": "/tmp"}}`, `{"scheduler": {"workers": 100}}`} {
+		body := fmt.Sprintf(`{"seeds": [{"url": "example.com"}], "config": %s}`, conf)
+		if status := doJSON(t, http.MethodPost, api.URL+"/jobs", body, &job); status != http.StatusBadRequest {
+			t.Errorf("config %s should not be overridden by job, got %d %v", conf, status, job)
+		}
+	}
+
+	body := fmt.Sprintf(`{"seeds": [{"url": %q}], "config": {"depth": 2, "graph": {"enabled": true}}}`, site.URL+"/")
+	if status := doJSON(t, http.MethodPost, api.URL+"/jobs", body, &job); status != http.StatusCreated {
+		t.Fatalf("job should be created, got %d %v", status, job)
+	}
+	id := job["id"].(string)
+
+	job = waitJob(t, api.URL, id)
+	if job["state"] != JobCompleted {
+		t.Fatalf("job should be completed, got %v", job)
+	}
+	if stats, _ := job["stats"].([]interface{}); len(stats) != 1 {
+		t.Errorf("finished job should have final stats, got %v", job["stats"])
+	}
+	s.mux.RLock()
+	processes := s.jobs[id].processes
+	s.mux.RUnlock()
+	if processes != nil {
+		t.Error("crawls of finished job should be released")
+	}
+	if status := doJSON(t, http.MethodGet, api.URL+"/jobs/"+id+"/events", "", nil); status != http.StatusConflict {
+		t.Errorf("finished job should have no events, got %d", status)
+	}
+
+	results := make([]services.CrawlerResult, 0)
+	doJSON(t, http.MethodGet, api.URL+"/jobs/"+id+"/result", "", &results)
+	if len(results) != 1 || results[0].InnerLinksCount != 2 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	res, err := http.Get(api.URL + "/jobs/" + id + "/result?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if !bytes.HasPrefix(csv, []byte("domain,url,status_code")) || bytes.Count(csv, []byte("\n")) != 3 {
+		t.Errorf("unexpected csv:\n%s", csv)
+	}
+
+	// slow job is canceled with partial results
+	body = fmt.Sprintf(`{"seeds": [{"url": %q}]}`, site.URL+"/slow")
+	doJSON(t, http.MethodPost, api.URL+"/jobs", body, &job)
+	slowId := job["id"].(string)
+	if status := doJSON(t, http.MethodGet, api.URL+"/jobs/"+slowId+"/result", "", &job); status != http.StatusConflict {
+		t.Errorf("results of running job should not be ready, got %d", status)
+	}
+	if status := doJSON(t, http.MethodPost, api.URL+"/jobs/"+slowId+"/cancel", "", &job); status != http.StatusAccepted {
+		t.Errorf("job should be canceled, got %d %v", status, job)
+	}
+	if job = waitJob(t, api.URL, slowId); job["state"] != JobCanceled {
+		t.Errorf("job should be canceled, got %v", job)
+	}
+
+	jobs := make([]map[string]interface{}, 0)
+	doJSON(t, http.MethodGet, api.URL+"/jobs", "", &jobs)
+	if len(jobs) != 2 || jobs[0]["id"] != id {
+		t.Errorf("unexpected jobs: %v", jobs)
+	}
+
+	// jobs survive restart
+	api.Close()
+	s, api = newTestServer(t, dir)
+	defer api.Close()
+	if len(s.order) != 2 {
+		t.Fatalf("jobs should be loaded, got %d", len(s.order))
+	}
+	if status := doJSON(t, http.MethodGet, api.URL+"/jobs/"+id+"/result", "", &results); status != http.StatusOK || len(results) != 1 {
+		t.Errorf("results should be loaded, got %d %+v", status, results)
+	}
+}
+
+func TestServerEvents(t *testing.T) {
+	gate := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			<-gate
+		}
+		fmt.Fprint(w, `<html><head><title>page</title></head><body><a href="/a">a</a></body></html>`)
+	}))
+	defer site.Close()
+
+	dir, err := ioutil.TempDir("", "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, api := newTestServer(t, dir)
+	defer api.Close()
+
+	if status := doJSON(t, http.MethodGet, api.URL+"/jobs/unknown/events", "", nil); status != http.StatusNotFound {
+		t.Errorf("events of unknown job should not be found, got %d", status)
+	}
+
+	submit := func() string {
+		job := map[string]interface{}{}
+		doJSON(t, http.MethodPost, api.URL+"/jobs", fmt.Sprintf(`{"seeds": [{"url": %q}]}`, site.URL+"/"), &job)
+		return job["id"].(string)
+	}
+
+	// server-sent events
+	id := submit()
+	res, err := http.Get(api.URL + "/jobs/" + id + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %s", ct)
+	}
+	gate <- struct{}{}
+	stream, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if bytes.Count(stream, []byte("event: page\ndata: {")) != 2 || !bytes.Contains(stream, []byte("event: done\ndata: {")) {
+		t.Errorf("unexpected event stream:\n%s", stream)
+	}
+
+	// websocket
+	id = submit()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(api.URL, "http")+"/jobs/"+id+"/ws", "", api.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	gate <- struct{}{}
+	types := make([]string, 0)
+	for {
+		e := services.CrawlerEvent{}
+		if err := websocket.JSON.Receive(ws, &e); err != nil {
+			break
+		}
+		types = append(types, e.Type)
+	}
+	if len(types) != 4 || types[0] != services.EventPage || types[len(types)-1] != services.EventDone {
+		t.Errorf("unexpected websocket events: %v", types)
+	}
+
+	waitJob(t, api.URL, id)
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/config"
+	"strings"
+	"sync"
+)
+
+// AccessibilityIssue is a problem found by AccessibilityRule, severity is error, warning or notice
+type AccessibilityIssue struct {
+	Severity string
+	Message  string
+}
+
+// AccessibilityRule checks parsed html of page, own rules are added by RegisterAccessibilityRule
+type AccessibilityRule interface {
+	// Name is check name of findings and key of crawler.accessibility.rules config
+	Name() string
+	Check(body *goquery.Document) []AccessibilityIssue
+}
+
+var (
+	accessibilityRules = []AccessibilityRule{
+		langRule{},
+		imageAltRule{},
+		linkTextRule{},
+		inputLabelRule{},
+		duplicateIdRule{},
+		headingOrderRule{},
+	}
+	accessibilityRulesMux sync.RWMutex
+)
+
+// RegisterAccessibilityRule adds rule to checks of all crawls, rule with the same name is replaced
+func RegisterAccessibilityRule(rule AccessibilityRule) {
+	accessibilityRulesMux.Lock()
+	defer accessibilityRulesMux.Unlock()
+
+	for i, r := range accessibilityRules {
+		if r.Name() == rule.Name() {
+			accessibilityRules[i] = rule
+			return
+		}
+	}
+	accessibilityRules = append(accessibilityRules, rule)
+}
+
+// checkAccessibilityConfig rejects names of enabled accessibility checks which are not registered rules
+func checkAccessibilityConfig(conf config.AccessibilityConfig) error {
+	if !conf.Enabled {
+		return nil
+	}
+
+	accessibilityRulesMux.RLock()
+	defer accessibilityRulesMux.RUnlock()
+
+	unknown := make([]string, 0)
+	for _, name := range conf.Rules {
+		found := false
+		for _, r := range accessibilityRules {
+			found = found || r.Name() == name
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown accessibility rules: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// parseGoqueryAccessibility runs enabled rules on page, empty rules config means all rules,
+// url of findings is set by addAccessibilityFindings
+func (p *CrawlerProcess) parseGoqueryAccessibility(body *goquery.Document) []crawlerFinding {
+	enabled := p.crawlerService.conf.Accessibility.Rules
+
+	accessibilityRulesMux.RLock()
+	rules := make([]AccessibilityRule, 0, len(accessibilityRules))
+	for _, r := range accessibilityRules {
+		if len(enabled) == 0 || containsString(enabled, r.Name()) {
+			rules = append(rules, r)
+		}
+	}
+	accessibilityRulesMux.RUnlock()
+
+	findings := make([]crawlerFinding, 0)
+	for _, r := range rules {
+		for _, issue := range r.Check(body) {
+			findings = append(findings, crawlerFinding{
+				Check:    r.Name(),
+				Severity: issue.Severity,
+				Message:  issue.Message,
+			})
+		}
+	}
+
+	return findings
+}
+
+func (p *CrawlerProcess) addAccessibilityFindings(pageUrl string, findings []crawlerFinding) {
+	for i := range findings {
+		findings[i].Url = pageUrl
+	}
+
+	p.mux.Lock()
+	p.accessibilityFindings = append(p.accessibilityFindings, findings...)
+	p.mux.Unlock()
+}
+
+func (p *CrawlerProcess) getAccessibilityFindings() []crawlerFinding {
+	if !p.crawlerService.conf.Accessibility.Enabled {
+		return nil
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	res := append([]crawlerFinding{}, p.accessibilityFindings...)
+	sortFindings(res)
+
+	return res
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAccessibleName checks attributes which name element for screen readers
+func hasAccessibleName(s *goquery.Selection) bool {
+	for _, attr := range []string{"aria-label", "aria-labelledby", "title"} {
+		if strings.TrimSpace(s.AttrOr(attr, "")) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+type langRule struct{}
+
+func (langRule) Name() string { return "html-lang" }
+
+func (langRule) Check(body *goquery.Document) []AccessibilityIssue {
+	if strings.TrimSpace(body.Find("html").AttrOr("lang", "")) == "" {
+		return []AccessibilityIssue{{severityError, "html element has no lang attribute"}}
+	}
+	return nil
+}
+
+type imageAltRule struct{}
+
+func (imageAltRule) Name() string { return "image-alt" }
+
+// Check accepts empty alt, it marks decorative images
+func (imageAltRule) Check(body *goquery.Document) []AccessibilityIssue {
+	res := make([]AccessibilityIssue, 0)
+	body.Find(`img, input[type="image"], area[href]`).Each(func(i int, s *goquery.Selection) {
+		if _, ok := s.Attr("alt"); !ok && !hasAccessibleName(s) {
+			res = append(res, AccessibilityIssue{severityError, fmt.Sprintf("%s without alt: %s", goquery.NodeName(s), s.AttrOr("src", s.AttrOr("href", "")))})
+		}
+	})
+	return res
+}
+
+type linkTextRule struct{}
+
+func (linkTextRule) Name() string { return "link-text" }
+
+func (linkTextRule) Check(body *goquery.Document) []AccessibilityIssue {
+	res := make([]AccessibilityIssue, 0)
+	body.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+		if strings.TrimSpace(s.Text()) != "" || hasAccessibleName(s) {
+			return
+		}
+		// image links are named by alt of image
+		named := false
+		s.Find("img[alt]").EachWithBreak(func(i int, img *goquery.Selection) bool {
+			named = strings.TrimSpace(img.AttrOr("alt", "")) != ""
+			return !named
+		})
+		if !named {
+			res = append(res, AccessibilityIssue{severityError, fmt.Sprintf("link without text: %s", s.AttrOr("href", ""))})
+		}
+	})
+	return res
+}
+
+type inputLabelRule struct{}
+
+func (inputLabelRule) Name() string { return "input-label" }
+
+func (inputLabelRule) Check(body *goquery.Document) []AccessibilityIssue {
+	labels := make(map[string]bool)
+	body.Find("label[for]").Each(func(i int, s *goquery.Selection) {
+		labels[s.AttrOr("for", "")] = true
+	})
+
+	res := make([]AccessibilityIssue, 0)
+	body.Find("input, select, textarea").Each(func(i int, s *goquery.Selection) {
+		switch strings.ToLower(s.AttrOr("type", "")) {
+		// buttons are named by value, image inputs are checked by image-alt
+		case "hidden", "submit", "reset", "button", "image":
+			return
+		}
+
+		id := s.AttrOr("id", "")
+		if (id != "" && labels[id]) || s.Closest("label").Length() > 0 || hasAccessibleName(s) {
+			return
+		}
+
+		name := s.AttrOr("name", id)
+		res = append(res, AccessibilityIssue{severityError, fmt.Sprintf("%s without label: %s", goquery.NodeName(s), name)})
+	})
+	return res
+}
+
+type duplicateIdRule struct{}
+
+func (duplicateIdRule) Name() string { return "duplicate-id" }
+
+func (duplicateIdRule) Check(body *goquery.Document) []AccessibilityIssue {
+	ids := make([]string, 0)
+	counts := make(map[string]int)
+	body.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if counts[id] == 0 {
+			ids = append(ids, id)
+		}
+		counts[id]++
+	})
+
+	res := make([]AccessibilityIssue, 0)
+	for _, id := range ids {
+		if counts[id] > 1 {
+			res = append(res, AccessibilityIssue{severityWarning, fmt.Sprintf("id %q is used %d times", id, counts[id])})
+		}
+	}
+	return res
+}
+
+type headingOrderRule struct{}
+
+func (headingOrderRule) Name() string { return "heading-order" }
+
+// Check reports headings which skip levels down, e.g. h2 followed by h4
+func (headingOrderRule) Check(body *goquery.Document) []AccessibilityIssue {
+	res := make([]AccessibilityIssue, 0)
+	prev := 0
+	body.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		level := int(goquery.NodeName(s)[1] - '0')
+		if prev > 0 && level > prev+1 {
+			res = append(res, AccessibilityIssue{severityWarning, fmt.Sprintf("h%d follows h%d: %s", level, prev, strings.TrimSpace(s.Text()))})
+		}
+		prev = level
+	})
+	return res
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/config"
+	"strings"
+	"testing"
+)
+
+func TestAccessibilityRules(t *testing.T) {
+	html := `<html><body>
+		<h1>Title</h1><h3>Skipped</h3>
+		<img src="/a.png"><img src="/b.png" alt="">
+		<a href="/empty"></a><a href="/img"><img src="/c.png" alt="Home"></a>
+		<label for="email">Email</label><input id="email"><input name="phone"><label>Name <input name="name"></label>
+		<input type="submit"><p id="x"></p><p id="x"></p>
+	</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"html-lang":     {"html element has no lang attribute"},
+		"image-alt":     {"img without alt: /a.png"},
+		"link-text":     {"link without text: /empty"},
+		"input-label":   {"input without label: phone"},
+		"duplicate-id":  {`id "x" is used 2 times`},
+		"heading-order": {"h3 follows h1: Skipped"},
+	}
+
+	for _, r := range accessibilityRules {
+		issues := r.Check(doc)
+		messages := make([]string, 0, len(issues))
+		for _, issue := range issues {
+			messages = append(messages, issue.Message)
+		}
+		if strings.Join(messages, "|") != strings.Join(expected[r.Name()], "|") {
+			t.Errorf("rule %s: expected %v, got %v", r.Name(), expected[r.Name()], messages)
+		}
+	}
+}
+
+// titleRule is own rule of test
+type titleRule struct {
+	message string
+}
+
+func (titleRule) Name() string { return "title" }
+
+func (r titleRule) Check(body *goquery.Document) []AccessibilityIssue {
+	if body.Find("title").Length() == 0 {
+		return []AccessibilityIssue{{Severity: severityWarning, Message: r.message}}
+	}
+	return nil
+}
+
+func TestRegisterAccessibilityRule(t *testing.T) {
+	builtin := append([]AccessibilityRule{}, accessibilityRules...)
+	defer func() {
+		accessibilityRulesMux.Lock()
+		accessibilityRules = builtin
+		accessibilityRulesMux.Unlock()
+	}()
+
+	conf := config.AccessibilityConfig{Enabled: true, Rules: []string{"html-lang", "title"}}
+	if err := checkAccessibilityConfig(conf); err == nil || err.Error() != "unknown accessibility rules: title" {
+		t.Errorf("unregistered rule should be rejected, got %v", err)
+	}
+
+	RegisterAccessibilityRule(titleRule{message: "old"})
+	RegisterAccessibilityRule(titleRule{message: "page has no title"})
+	if len(accessibilityRules) != len(builtin)+1 {
+		t.Errorf("rule with the same name should be replaced, got %d rules", len(accessibilityRules))
+	}
+	if err := checkAccessibilityConfig(conf); err != nil {
+		t.Errorf("registered rule should be valid, got %v", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html lang="en"><body><img src="/a.png"></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &CrawlerProcess{crawlerService: &CrawlerService{conf: config.CrawlerConfig{Accessibility: conf}}}
+	findings := p.parseGoqueryAccessibility(doc)
+	if len(findings) != 1 || findings[0].Check != "title" || findings[0].Message != "page has no title" {
+		t.Errorf("only enabled rules should run, got %v", findings)
+	}
+}
+
+func TestCheckAccessibilityConfig(t *testing.T) {
+	if err := checkAccessibilityConfig(config.AccessibilityConfig{Rules: []string{"typo"}}); err != nil {
+		t.Errorf("rules of disabled accessibility should not be checked, got %v", err)
+	}
+	if err := checkAccessibilityConfig(config.AccessibilityConfig{Enabled: true}); err != nil {
+		t.Errorf("empty rules should enable all rules, got %v", err)
+	}
+	err := checkAccessibilityConfig(config.AccessibilityConfig{Enabled: true, Rules: []string{"image-alt", "img-alt", "lang"}})
+	if err == nil || err.Error() != "unknown accessibility rules: img-alt, lang" {
+		t.Errorf("unknown rules should be rejected, got %v", err)
+	}
+}
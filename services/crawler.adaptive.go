@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAdaptiveMaxFactor     = 4 // max workers are 4 times workers by default
+	defaultAdaptiveTargetLatency = time.Second
+	maxConcurrencyTimeline       = 1000 // the oldest adjustments are dropped from timeline
+)
+
+// concurrencyChange is adjustment of concurrency limit of crawl
+type concurrencyChange struct {
+	Time      time.Time `json:"time"`
+	Limit     int       `json:"limit"`
+	Reason    string    `json:"reason"`
+	LatencyMs int64     `json:"latency_ms"`
+}
+
+type concurrencyReport struct {
+	Min      int                 `json:"min"`
+	Max      int                 `json:"max"`
+	Final    int                 `json:"final"`
+	Timeline []concurrencyChange `json:"timeline"`
+}
+
+// concurrencyLimiter limits count of concurrent requests of crawl, in adaptive mode the limit
+// is increased by one after limit fast responses in row and halved on slow, failed,
+// 429 or 503 responses (AIMD)
+type concurrencyLimiter struct {
+	adaptive     bool
+	limit        int
+	min          int
+	max          int
+	target       time.Duration
+	inFlight     int
+	paused       bool
+	successes    int       // fast responses since the last adjustment
+	lastDecrease time.Time // responses of requests started before are not decreasing again
+	timeline     []concurrencyChange
+	wake         chan struct{} // closed when a request is finished or limit is changed
+	mux          sync.Mutex
+}
+
+// newConcurrencyLimiter returns fixed limiter of workers or adaptive one starting at workers
+func newConcurrencyLimiter(workers int, conf config.AdaptiveConfig) *concurrencyLimiter {
+	l := &concurrencyLimiter{
+		limit: workers,
+		min:   workers,
+		max:   workers,
+		wake:  make(chan struct{}),
+	}
+	if !conf.Enabled {
+		return l
+	}
+
+	l.adaptive = true
+	l.min = orDefault(conf.MinWorkers, 1)
+	l.max = orDefault(conf.MaxWorkers, workers*defaultAdaptiveMaxFactor)
+	if l.max < l.min {
+		l.max = l.min
+	}
+	if l.limit < l.min {
+		l.limit = l.min
+	}
+	if l.limit > l.max {
+		l.limit = l.max
+	}
+	l.target = defaultAdaptiveTargetLatency
+	if conf.TargetLatencyMs > 0 {
+		l.target = time.Duration(conf.TargetLatencyMs) * time.Millisecond
+	}
+	l.timeline = []concurrencyChange{{Time: time.Now(), Limit: l.limit, Reason: "start"}}
+
+	return l
+}
+
+// workers returns count of worker goroutines needed for the highest limit
+func (l *concurrencyLimiter) workers() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.limit > l.max {
+		return l.limit
+	}
+	return l.max
+}
+
+// acquire waits until count of requests is below limit and limiter is not paused
+func (l *concurrencyLimiter) acquire(ctx context.Context) error {
+	for {
+		l.mux.Lock()
+		if !l.paused && l.inFlight < l.limit {
+			l.inFlight++
+			l.mux.Unlock()
+			return nil
+		}
+		wake := l.wake
+		l.mux.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *concurrencyLimiter) release() {
+	l.mux.Lock()
+	l.inFlight--
+	l.notify()
+	l.mux.Unlock()
+}
+
+func (l *concurrencyLimiter) setPaused(paused bool) {
+	l.mux.Lock()
+	l.paused = paused
+	l.notify()
+	l.mux.Unlock()
+}
+
+// setWorkers sets limit, bounds of adaptive limit are extended to it
+func (l *concurrencyLimiter) setWorkers(host string, n int) {
+	l.mux.Lock()
+	if n < l.min || !l.adaptive {
+		l.min = n
+	}
+	if n > l.max || !l.adaptive {
+		l.max = n
+	}
+	l.successes = 0
+	change := l.setLimit(host, n, "set workers", 0)
+	l.mux.Unlock()
+
+	logLimit(change)
+}
+
+func (l *concurrencyLimiter) getLimit() (int, bool) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return l.limit, l.paused
+}
+
+// notify wakes waiting workers, it has to be called under l.mux lock
+func (l *concurrencyLimiter) notify() {
+	close(l.wake)
+	l.wake = make(chan struct{})
+}
+
+// observe adjusts limit by response of request started at start, statusCode is 0 for failed request
+func (l *concurrencyLimiter) observe(host string, start time.Time, latency time.Duration, statusCode int) {
+	if !l.adaptive {
+		return
+	}
+
+	l.mux.Lock()
+	change := l.adjust(host, start, latency, statusCode)
+	l.mux.Unlock()
+
+	logLimit(change)
+}
+
+// adjust changes limit by response, it returns message of change or empty string,
+// it has to be called under l.mux lock
+func (l *concurrencyLimiter) adjust(host string, start time.Time, latency time.Duration, statusCode int) string {
+	reason := ""
+	switch {
+	case statusCode == 0:
+		reason = "request failed"
+	case statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable:
+		reason = http.StatusText(statusCode)
+	case latency > l.target:
+		reason = "slow response"
+	}
+
+	if reason != "" {
+		l.successes = 0
+		// requests started before the last decrease were sent with higher limit
+		if start.Before(l.lastDecrease) || l.limit <= l.min {
+			return ""
+		}
+		limit := l.limit / 2
+		if limit < l.min {
+			limit = l.min
+		}
+		l.lastDecrease = time.Now()
+		return l.setLimit(host, limit, reason, latency)
+	}
+
+	l.successes++
+	if l.successes >= l.limit && l.limit < l.max {
+		l.successes = 0
+		return l.setLimit(host, l.limit+1, "fast responses", latency)
+	}
+	return ""
+}
+
+// setLimit changes limit and returns message of change, it has to be called under l.mux lock
+// and the message is logged after unlock, log output can read stats of limiter
+func (l *concurrencyLimiter) setLimit(host string, limit int, reason string, latency time.Duration) string {
+	change := fmt.Sprintf("%s concurrency %d -> %d: %s, latency %v", host, l.limit, limit, reason, latency)
+
+	l.limit = limit
+	if len(l.timeline) >= maxConcurrencyTimeline {
+		l.timeline = append(l.timeline[:0], l.timeline[len(l.timeline)-maxConcurrencyTimeline+1:]...)
+	}
+	l.timeline = append(l.timeline, concurrencyChange{
+		Time:      time.Now(),
+		Limit:     limit,
+		Reason:    reason,
+		LatencyMs: int64(latency / time.Millisecond),
+	})
+	l.notify()
+
+	return change
+}
+
+// logLimit logs change of limit returned by setLimit
+func logLimit(change string) {
+	if change != "" {
+		log.WithTrace("CrawlerService", "concurrencyLimiter", "setLimit").Info(change)
+	}
+}
+
+func (l *concurrencyLimiter) getReport() *concurrencyReport {
+	if !l.adaptive {
+		return nil
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	return &concurrencyReport{
+		Min:      l.min,
+		Max:      l.max,
+		Final:    l.limit,
+		Timeline: append([]concurrencyChange{}, l.timeline...),
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiterAimd(t *testing.T) {
+	l := newConcurrencyLimiter(2, config.AdaptiveConfig{Enabled: true, MaxWorkers: 4, TargetLatencyMs: 100})
+	if l.workers() != 4 {
+		t.Fatalf("expected 4 workers, got %d", l.workers())
+	}
+
+	fast := 10 * time.Millisecond
+	for i := 0; i < 2+3+4; i++ {
+		l.observe("example.com", time.Now(), fast, 200)
+	}
+	if l.limit != 4 {
+		t.Fatalf("limit should grow to max 4, got %d", l.limit)
+	}
+
+	before := time.Now()
+	time.Sleep(time.Millisecond)
+	l.observe("example.com", time.Now(), fast, 429)
+	if l.limit != 2 {
+		t.Fatalf("limit should be halved on 429, got %d", l.limit)
+	}
+
+	// response of request sent before the decrease does not decrease again
+	l.observe("example.com", before, time.Second, 200)
+	if l.limit != 2 {
+		t.Fatalf("limit should not be decreased twice, got %d", l.limit)
+	}
+
+	l.observe("example.com", time.Now(), time.Second, 200)
+	l.observe("example.com", time.Now(), 0, 0)
+	if l.limit != 1 {
+		t.Fatalf("limit should not be below min 1, got %d", l.limit)
+	}
+
+	report := l.getReport()
+	if report.Final != 1 || len(report.Timeline) != 5 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestConcurrencyLimiterTimeline(t *testing.T) {
+	l := newConcurrencyLimiter(1, config.AdaptiveConfig{Enabled: true, MaxWorkers: 2})
+	for i := 0; i < maxConcurrencyTimeline+10; i++ {
+		l.setWorkers("example.com", 1+i%2)
+	}
+
+	timeline := l.getReport().Timeline
+	if len(timeline) != maxConcurrencyTimeline {
+		t.Fatalf("timeline should be capped to %d changes, got %d", maxConcurrencyTimeline, len(timeline))
+	}
+	if last := timeline[len(timeline)-1]; last.Limit != 2 || last.Reason != "set workers" {
+		t.Errorf("timeline should keep the last change, got %+v", last)
+	}
+}
+
+// limitWriter reads limit on every log line like progress dashboard, locked is closed
+// if limit cannot be read because limiter logs under lock
+type limitWriter struct {
+	l      *concurrencyLimiter
+	locked chan struct{}
+	once   sync.Once
+}
+
+func (w *limitWriter) Write(b []byte) (int, error) {
+	read := make(chan struct{})
+	go func() {
+		w.l.getLimit()
+		close(read)
+	}()
+
+	select {
+	case <-read:
+	case <-time.After(time.Second):
+		w.once.Do(func() { close(w.locked) })
+	}
+	return len(b), nil
+}
+
+func TestConcurrencyLimiterLogsWithoutLock(t *testing.T) {
+	l := newConcurrencyLimiter(1, config.AdaptiveConfig{Enabled: true, MaxWorkers: 2})
+	w := &limitWriter{l: l, locked: make(chan struct{})}
+	log.SetOutput(w)
+	defer log.SetOutput(os.Stderr)
+
+	l.setWorkers("example.com", 2)
+	l.observe("example.com", time.Now(), 0, 0)
+
+	select {
+	case <-w.locked:
+		t.Error("limiter should not log under lock")
+	default:
+	}
+}
+
+func TestConcurrencyLimiterAcquire(t *testing.T) {
+	l := newConcurrencyLimiter(1, config.AdaptiveConfig{})
+	if l.getReport() != nil {
+		t.Error("fixed limiter should not report")
+	}
+
+	ctx := context.Background()
+	if err := l.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		l.acquire(ctx)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquire should wait for release")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	l.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("acquire should continue after release")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.acquire(canceled); err == nil {
+		t.Error("acquire should fail on canceled context")
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// referrers of every url are capped to keep memory bounded
+const maxReferrers = 10
+
+// brokenLink is page that failed or a missing anchor on existing page
+type brokenLink struct {
+	Url        string   `json:"url"`
+	StatusCode int      `json:"status_code,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	Fragment   string   `json:"fragment,omitempty"`
+	Referrers  []string `json:"referrers"`
+}
+
+// parseGoqueryAnchors returns element ids and names of <a name> to validate fragment links
+func (p *CrawlerProcess) parseGoqueryAnchors(body *goquery.Document) map[string]bool {
+	res := make(map[string]bool)
+	body.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		res[s.AttrOr("id", "")] = true
+	})
+	body.Find("a[name]").Each(func(i int, s *goquery.Selection) {
+		res[s.AttrOr("name", "")] = true
+	})
+	return res
+}
+
+// addReferrer remembers page linking to url, it has to be called under p.mux lock
+func (p *CrawlerProcess) addReferrer(url, referrer string) {
+	if referrer == "" {
+		return
+	}
+	refs := p.referrers[url]
+	if len(refs) >= maxReferrers {
+		return
+	}
+	for _, r := range refs {
+		if r == referrer {
+			return
+		}
+	}
+	p.referrers[url] = append(refs, referrer)
+}
+
+// addFragment remembers fragment link to inner page
+func (p *CrawlerProcess) addFragment(pageUrl, fragment, referrer string) {
+	if !p.crawlerService.conf.CheckFragments || fragment == "" {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	fragments, ok := p.fragments[pageUrl]
+	if !ok {
+		fragments = make(map[string][]string)
+		p.fragments[pageUrl] = fragments
+	}
+
+	refs := fragments[fragment]
+	if len(refs) >= maxReferrers {
+		return
+	}
+	for _, r := range refs {
+		if r == referrer {
+			return
+		}
+	}
+	fragments[fragment] = append(refs, referrer)
+}
+
+func (p *CrawlerProcess) addFailure(link crawlerLink, err error) {
+	p.mux.Lock()
+	p.failures[link.Url] = err.Error()
+	if p.bounded() {
+		p.addReferrer(link.Url, link.Referrer)
+	}
+	p.mux.Unlock()
+}
+
+// getBrokenLinks returns failed pages, pages with error status and missing anchors
+func (p *CrawlerProcess) getBrokenLinks() []brokenLink {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	res := make([]brokenLink, 0)
+	referrers := func(refs []string) []string {
+		res := append([]string{}, refs...)
+		sort.Strings(res)
+		return res
+	}
+
+	for l, e := range p.failures {
+		res = append(res, brokenLink{
+			Url:       l,
+			Error:     e,
+			Referrers: referrers(p.referrers[l]),
+		})
+	}
+
+	for l, d := range p.data {
+		if d.StatusCode >= http.StatusBadRequest {
+			res = append(res, brokenLink{
+				Url:        l,
+				StatusCode: d.StatusCode,
+				Referrers:  referrers(p.referrers[l]),
+			})
+		}
+	}
+
+	for l, fragments := range p.fragments {
+		d, ok := p.data[l]
+		if !ok || d.Anchors == nil || d.StatusCode >= http.StatusBadRequest {
+			continue
+		}
+		for fragment, refs := range fragments {
+			// #top scrolls to the top of page without element
+			if d.Anchors[fragment] || strings.EqualFold(fragment, "top") {
+				continue
+			}
+			if decoded, err := url.PathUnescape(fragment); err == nil && d.Anchors[decoded] {
+				continue
+			}
+			res = append(res, brokenLink{
+				Url:        l,
+				StatusCode: d.StatusCode,
+				Fragment:   fragment,
+				Referrers:  referrers(refs),
+			})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Url != res[j].Url {
+			return res[i].Url < res[j].Url
+		}
+		return res[i].Fragment < res[j].Fragment
+	})
+
+	return res
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBrokenLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/guide#install">i</a><a href="/guide#missing">m</a><a href="#top">t</a>
+				<a href="/gone">g</a><a href="/guide">guide</a><a href="https://ext.example/">ext</a></body></html>`)
+		case "/guide":
+			fmt.Fprint(w, `<html><body><h2 id="install">Install</h2><a name="Usage%20notes"></a>
+				<a href="/gone">g</a><a href="#Usage%20notes">u</a><a href="/#missing">m</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	conf := config.CrawlerConfig{Depth: 3, Workers: 2, CheckFragments: true}
+	s := newTestCrawlerService(srv, conf)
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+
+	expected := []brokenLink{
+		{Url: srv.URL + "/", StatusCode: 200, Fragment: "missing", Referrers: []string{srv.URL + "/guide"}},
+		{Url: srv.URL + "/gone", StatusCode: 404, Referrers: []string{srv.URL + "/", srv.URL + "/guide"}},
+		{Url: srv.URL + "/guide", StatusCode: 200, Fragment: "missing", Referrers: []string{srv.URL + "/"}},
+	}
+	if fmt.Sprint(res.BrokenLinks) != fmt.Sprint(expected) {
+		t.Errorf("expected broken links %v, got %v", expected, res.BrokenLinks)
+	}
+
+	if _, ok := p.referrers["https://ext.example/"]; ok {
+		t.Error("referrers of external links should not be kept without external check")
+	}
+}
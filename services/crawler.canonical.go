@@ -0,0 +1,243 @@
+package services
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/log"
+	"go-link-crawler/utils"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	reHeadTag = regexp.MustCompile(`(?is)<(?:meta|link)\s[^>]*>`)
+	reTagAttr = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	reRefresh = regexp.MustCompile(`(?is)^\s*\d*(?:\.\d*)?\s*[;,]?\s*(?:url\s*=\s*)?["']?([^"']*)["']?\s*$`)
+)
+
+const (
+	redirectHttp        = "http"
+	redirectMetaRefresh = "meta-refresh"
+)
+
+// crawlerRedirect is a hop from requested url to another one
+type crawlerRedirect struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+type canonicalReport struct {
+	Chains    [][]string       `json:"chains"` // pages with canonical pointing to page with another canonical
+	Conflicts []crawlerFinding `json:"conflicts"`
+}
+
+// parseReHeadTags returns <meta> refresh target and <link rel="canonical"> urls by regex
+func (p *CrawlerProcess) parseReHeadTags(body []byte) (string, []string) {
+	refresh := ""
+	canonicals := make([]string, 0)
+	for _, tag := range reHeadTag.FindAll(body, -1) {
+		attrs := make(map[string]string)
+		for _, m := range reTagAttr.FindAllSubmatch(tag, -1) {
+			attrs[strings.ToLower(string(m[1]))] = string(m[2]) + string(m[3]) + string(m[4])
+		}
+
+		if strings.EqualFold(attrs["http-equiv"], "refresh") && refresh == "" {
+			refresh = parseRefreshUrl(attrs["content"])
+		}
+		if hasRel(strings.ToLower(attrs["rel"]), "canonical") && attrs["href"] != "" {
+			canonicals = append(canonicals, strings.TrimSpace(attrs["href"]))
+		}
+	}
+	return refresh, canonicals
+}
+
+// parseGoqueryHeadTags returns <meta> refresh target and <link rel="canonical"> urls
+func (p *CrawlerProcess) parseGoqueryHeadTags(body *goquery.Document) (string, []string) {
+	refresh := ""
+	body.Find("meta[http-equiv]").Each(func(i int, s *goquery.Selection) {
+		if strings.EqualFold(s.AttrOr("http-equiv", ""), "refresh") && refresh == "" {
+			refresh = parseRefreshUrl(s.AttrOr("content", ""))
+		}
+	})
+
+	canonicals := make([]string, 0)
+	body.Find("link[rel]").Each(func(i int, s *goquery.Selection) {
+		if href := strings.TrimSpace(s.AttrOr("href", "")); href != "" && hasRel(strings.ToLower(s.AttrOr("rel", "")), "canonical") {
+			canonicals = append(canonicals, href)
+		}
+	})
+
+	return refresh, canonicals
+}
+
+// parseRefreshUrl returns url from `0;url=...` content of meta refresh
+func parseRefreshUrl(content string) string {
+	matches := reRefresh.FindStringSubmatch(content)
+	if len(matches) > 1 {
+		return strings.TrimSpace(matches[1])
+	}
+	return ""
+}
+
+// resolveUrls makes unique full urls from page links
+func (p *CrawlerProcess) resolveUrls(links []string, pageUrl string) []string {
+	res := make([]string, 0, len(links))
+	seen := make(map[string]bool)
+	for _, l := range links {
+		full := utils.RelativeUrlToFull(l, pageUrl, p.uri)
+		if full != "" && !seen[full] {
+			seen[full] = true
+			res = append(res, full)
+		}
+	}
+	return res
+}
+
+func (p *CrawlerProcess) addRedirect(from, to, redirectType string) {
+	log.WithTrace("CrawlerService", "CrawlerProcess", "addRedirect").Tracef("%s redirect from %s to %s", redirectType, from, to)
+
+	p.mux.Lock()
+	p.redirects = append(p.redirects, crawlerRedirect{
+		From: from,
+		To:   to,
+		Type: redirectType,
+	})
+	p.mux.Unlock()
+}
+
+// processRefresh follows meta refresh target like redirect, it does not increase depth
+func (p *CrawlerProcess) processRefresh(link crawlerLink, pageUrl, refresh string) {
+	target := utils.RelativeUrlToFull(refresh, pageUrl, p.uri)
+	if target == "" || target == pageUrl {
+		return
+	}
+
+	p.addRedirect(pageUrl, target, redirectMetaRefresh)
+	if p.inScope(target) {
+		p.pushLink(target, pageUrl, link.Depth)
+	}
+}
+
+// isCanonicalDuplicate uses canonical url as key of visited set,
+// page is duplicate if its canonical page is already known by crawler,
+// unknown canonical page is queued at depth of the page
+func (p *CrawlerProcess) isCanonicalDuplicate(link crawlerLink, canonicals []string) bool {
+	if !p.crawlerService.conf.Canonical.Dedup || len(canonicals) != 1 {
+		return false
+	}
+
+	canonical := canonicals[0]
+	if canonical == link.Url || !p.inScope(canonical) {
+		return false
+	}
+
+	p.mux.Lock()
+	if !p.markVisited(canonical, link.Depth) {
+		p.mux.Unlock()
+		return true
+	}
+	listed := p.listed[canonical]
+	p.mux.Unlock()
+
+	p.queueLink(crawlerLink{Url: canonical, Depth: link.Depth, Referrer: link.Url}, listed)
+
+	return false
+}
+
+func (p *CrawlerProcess) getCanonicalReport() *canonicalReport {
+	// canonicals of spilled pages are only in pages file
+	if p.pages != nil {
+		return nil
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	canonicalOf := make(map[string]string)
+	for l, d := range p.data {
+		if len(d.Canonicals) == 1 {
+			canonicalOf[l] = d.Canonicals[0]
+		}
+	}
+
+	res := &canonicalReport{
+		Chains:    [][]string{},
+		Conflicts: []crawlerFinding{},
+	}
+
+	add := func(check, severity, url, format string, args ...interface{}) {
+		res.Conflicts = append(res.Conflicts, crawlerFinding{
+			Check:    check,
+			Severity: severity,
+			Url:      url,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	for l, d := range p.data {
+		if len(d.Canonicals) > 1 {
+			add("canonical-multiple", severityError, l, "page has %d different canonicals: %s", len(d.Canonicals), strings.Join(d.Canonicals, ", "))
+			continue
+		}
+
+		canonical, ok := canonicalOf[l]
+		if !ok || canonical == l {
+			continue
+		}
+
+		if !p.inScope(canonical) {
+			add("canonical-external", severityWarning, l, "canonical points to other host %s", canonical)
+		}
+
+		if c, ok := p.data[canonical]; ok {
+			if c.StatusCode != http.StatusOK {
+				add("canonical-not-ok", severityError, l, "canonical %s responds with status %d", canonical, c.StatusCode)
+			}
+			if c.Robots != nil && !c.Robots.Indexable {
+				add("canonical-noindex", severityError, l, "canonical %s is not indexable", canonical)
+			}
+		}
+
+		// follow canonicals of canonical pages
+		chain := []string{l, canonical}
+		visited := map[string]bool{l: true, canonical: true}
+		for {
+			next, ok := canonicalOf[chain[len(chain)-1]]
+			if !ok || next == chain[len(chain)-1] {
+				break
+			}
+			chain = append(chain, next)
+			if visited[next] {
+				add("canonical-loop", severityError, l, "canonical loop: %s", strings.Join(chain, " -> "))
+				break
+			}
+			visited[next] = true
+		}
+		if len(chain) > 2 {
+			res.Chains = append(res.Chains, chain)
+		}
+	}
+
+	// pages without canonicals have no report
+	if len(canonicalOf) == 0 && len(res.Conflicts) == 0 {
+		return nil
+	}
+
+	sort.Slice(res.Chains, func(i, j int) bool { return res.Chains[i][0] < res.Chains[j][0] })
+	sortFindings(res.Conflicts)
+
+	return res
+}
+
+func (p *CrawlerProcess) getRedirects() []crawlerRedirect {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	res := append([]crawlerRedirect{}, p.redirects...)
+	sort.Slice(res, func(i, j int) bool { return res[i].From < res[j].From })
+
+	return res
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestParseRefreshUrl(t *testing.T) {
+	cases := map[string]string{
+		"0; URL='/next'":         "/next",
+		"5;url=https://site.com": "https://site.com",
+		`0.5, url="/a?b=c"`:      "/a?b=c",
+		"3":                      "",
+		"  /plain ":              "/plain",
+	}
+
+	for content, expected := range cases {
+		if res := parseRefreshUrl(content); res != expected {
+			t.Errorf("refresh %q expected %q, got %q", content, expected, res)
+		}
+	}
+}
+
+func TestCanonicalReport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><a href="/copy">copy</a><a href="/other">other</a></body></html>`)
+		case "/copy":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/gone"></head><body><a href="/from-copy">x</a></body></html>`)
+		case "/other":
+			fmt.Fprint(w, `<html><head><link rel="canonical" href="/gone"></head><body><a href="/from-other">x</a></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	conf := config.CrawlerConfig{Depth: 3, Workers: 1}
+	conf.Canonical.Dedup = true
+	s := newTestCrawlerService(srv, conf)
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+
+	if _, ok := res.Pages[srv.URL+"/gone"]; !ok {
+		t.Fatal("canonical page should be crawled")
+	}
+	// links of the second page with known canonical are not followed
+	_, copyLinks := res.Pages[srv.URL+"/from-copy"]
+	_, otherLinks := res.Pages[srv.URL+"/from-other"]
+	if copyLinks == otherLinks {
+		t.Errorf("links of only one page with the same canonical should be followed, got %v and %v", copyLinks, otherLinks)
+	}
+
+	conflicts := make(map[string]string)
+	for _, c := range res.Canonicals.Conflicts {
+		conflicts[c.Url] = c.Check
+	}
+	if conflicts[srv.URL+"/copy"] != "canonical-not-ok" || conflicts[srv.URL+"/other"] != "canonical-not-ok" {
+		t.Errorf("canonical to not found page should be reported, got %+v", res.Canonicals.Conflicts)
+	}
+}
+
+func TestCanonicalReportMultipleOnly(t *testing.T) {
+	uri, _ := url.Parse("https://site.com/")
+	p := &CrawlerProcess{
+		crawlerService: &CrawlerService{},
+		uri:            uri,
+		data: map[string]crawlerLinkData{
+			"https://site.com/a": {StatusCode: 200, Canonicals: []string{"https://site.com/a", "https://site.com/b"}},
+			"https://site.com/b": {StatusCode: 200, Canonicals: []string{"https://site.com/b", "https://site.com/c"}},
+		},
+	}
+
+	res := p.getCanonicalReport()
+	if res == nil || len(res.Conflicts) != 2 {
+		t.Fatalf("pages with multiple canonicals should be reported, got %+v", res)
+	}
+	for _, c := range res.Conflicts {
+		if c.Check != "canonical-multiple" {
+			t.Errorf("unexpected conflict %+v", c)
+		}
+	}
+
+	p.data = map[string]crawlerLinkData{"https://site.com/a": {StatusCode: 200}}
+	if p.getCanonicalReport() != nil {
+		t.Error("pages without canonicals should have no report")
+	}
+}
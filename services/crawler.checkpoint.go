@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"go-link-crawler/log"
+)
+
+// Checkpoint is state of interrupted crawl, crawl is resumed by CrawlerService.StartCheckpoint
+type Checkpoint struct {
+	Seed Seed `json:"seed"`
+	// Visited are urls found by crawl with depth, they are not queued again
+	Visited map[string]int `json:"visited"`
+	// Queue are links which were not crawled yet
+	Queue []CheckpointLink `json:"queue"`
+	// PagesFile is pages file of crawl with storage.pages_dir, resumed crawl appends pages to it
+	PagesFile string `json:"pages_file,omitempty"`
+}
+
+type CheckpointLink struct {
+	Url      string `json:"url"`
+	Depth    int    `json:"depth"`
+	Referrer string `json:"referrer,omitempty"`
+}
+
+// addInterrupted remembers link which was not crawled because crawl was stopped
+func (p *CrawlerProcess) addInterrupted(link crawlerLink) {
+	log.WithTrace("CrawlerService", "CrawlerProcess", "addInterrupted").Tracef("link is interrupted: %s", link.Url)
+
+	p.mux.Lock()
+	p.interrupted = append(p.interrupted, link)
+	p.mux.Unlock()
+}
+
+// Interrupted returns true if crawl was stopped before all links were crawled
+func (p *CrawlerProcess) Interrupted() bool {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	return p.stopped && (len(p.interrupted) > 0 || p.frontier.getStats().Length > 0)
+}
+
+// Checkpoint returns state of finished crawl to resume it, crawl has to be finished,
+// crawl with bloom visited set cannot be resumed because visited urls are unknown
+func (p *CrawlerProcess) Checkpoint() (*Checkpoint, error) {
+	<-p.done
+
+	if p.bounded() {
+		return nil, errors.New("crawl with bloom visited set cannot be checkpointed")
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	c := &Checkpoint{
+		Seed:    p.seed,
+		Visited: make(map[string]int, len(p.sitemap)),
+		Queue:   make([]CheckpointLink, 0),
+	}
+	for l, depth := range p.sitemap {
+		c.Visited[l] = depth
+	}
+	if p.pages != nil {
+		c.PagesFile = p.pages.path
+	}
+	for _, link := range append(append([]crawlerLink{}, p.interrupted...), p.frontier.queued()...) {
+		c.Queue = append(c.Queue, CheckpointLink{Url: link.Url, Depth: link.Depth, Referrer: link.Referrer})
+	}
+
+	return c, nil
+}
+
+// StartCheckpoint resumes interrupted crawl, result of resumed crawl has only pages crawled after resume
+func (s *CrawlerService) StartCheckpoint(c *Checkpoint) (*CrawlerProcess, error) {
+	if len(c.Queue) == 0 {
+		return nil, errors.New("checkpoint has no queued links")
+	}
+
+	if s.conf.Storage.Visited == VisitedBloom {
+		return nil, errors.New("crawl with bloom visited set cannot be resumed")
+	}
+
+	p, err := s.newCrawlerProcess(c.Seed, c.PagesFile)
+	if err != nil {
+		return p, err
+	}
+
+	for l, depth := range c.Visited {
+		p.sitemap[l] = depth
+	}
+	for _, link := range c.Queue {
+		p.sitemap[link.Url] = link.Depth
+		p.resumed = append(p.resumed, crawlerLink{Url: link.Url, Depth: link.Depth, Referrer: link.Referrer})
+	}
+
+	p.schedule()
+
+	return p, nil
+}
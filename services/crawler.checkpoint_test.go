@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCrawlerProcessCheckpoint(t *testing.T) {
+	slow := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-slow:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		fmt.Fprint(w, `<html><head><title>page</title></head><body><a href="/slow">slow</a><a href="/a">a</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &CrawlerService{
+		conf:       config.CrawlerConfig{Depth: 5, Workers: 2, StopGraceSec: 1},
+		httpClient: srv.Client(),
+		scheduler:  newScheduler(0, 0),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	p, err := s.StartSeed(Seed{Url: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p.Stats().Fetched < 2 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	start := time.Now()
+	p.Stop()
+	res := p.GetResult()
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("request in flight should be aborted after grace period, stop took %v", time.Since(start))
+	}
+	if !res.Interrupted {
+		t.Fatal("result should be interrupted")
+	}
+
+	c, err := p.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Queue) != 1 || c.Queue[0].Url != srv.URL+"/slow" {
+		t.Fatalf("slow page should be queued in checkpoint, got %+v", c.Queue)
+	}
+	if _, ok := c.Visited[srv.URL+"/a"]; !ok {
+		t.Error("crawled page should be visited in checkpoint")
+	}
+
+	close(slow)
+	resumed, err := s.StartCheckpoint(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = resumed.GetResult()
+	if res.Interrupted || res.InnerLinksCount != 1 {
+		t.Errorf("resumed crawl should crawl only queued page, got %d pages", res.InnerLinksCount)
+	}
+	if _, ok := res.Pages[srv.URL+"/slow"]; !ok {
+		t.Error("queued page should be crawled after resume")
+	}
+}
+
+func TestCheckpointLinksFoundAfterStop(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			close(started)
+			<-release
+			fmt.Fprint(w, `<html><body><a href="/found">found</a></body></html>`)
+		case "/found":
+			fmt.Fprint(w, `<html><body></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body><a href="/slow">slow</a></body></html>`)
+		}
+	}))
+	defer srv.Close()
+
+	s := newTestCrawlerService(srv, config.CrawlerConfig{Depth: 5, Workers: 2, StopGraceSec: 5})
+	defer s.Close()
+
+	p, err := s.StartSeed(Seed{Url: srv.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	// page in flight is finished in grace period and finds new link
+	p.Stop()
+	close(release)
+	res := p.GetResult()
+	if _, ok := res.Pages[srv.URL+"/slow"]; !ok {
+		t.Fatal("page in flight should be crawled in grace period")
+	}
+
+	c, err := p.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Queue) != 1 || c.Queue[0].Url != srv.URL+"/found" || c.Queue[0].Referrer != srv.URL+"/slow" {
+		t.Fatalf("link found after stop should be queued in checkpoint, got %+v", c.Queue)
+	}
+	if _, ok := c.Visited[srv.URL+"/found"]; !ok {
+		t.Error("link found after stop should be visited in checkpoint")
+	}
+}
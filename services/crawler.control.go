@@ -0,0 +1,137 @@
+package services
+
+import (
+	"go-link-crawler/log"
+	"time"
+)
+
+// states of crawl process
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StatePaused    = "paused"
+	StateStopping  = "stopping"
+	StateStopped   = "stopped"
+	StateCompleted = "completed"
+)
+
+const defaultStopGraceSec = 10
+
+// CrawlerStats is live progress of crawl process
+type CrawlerStats struct {
+	Domain         string        `json:"domain"`
+	State          string        `json:"state"`
+	Fetched        int           `json:"fetched"`
+	Queued         int           `json:"queued"`
+	InFlight       int           `json:"in_flight"`
+	Errors         int           `json:"errors"`
+	Workers        int           `json:"workers"`
+	Elapsed        time.Duration `json:"elapsed_ns"`
+	RequestsPerSec float32       `json:"requests_per_sec"`
+}
+
+// Pause stops taking new links from queue, requests in flight are finished
+func (p *CrawlerProcess) Pause() {
+	log.WithTrace("CrawlerService", "CrawlerProcess", "Pause").Infof("%s is paused", p.uri.Host)
+	p.limiter.setPaused(true)
+}
+
+// Resume continues paused crawl
+func (p *CrawlerProcess) Resume() {
+	log.WithTrace("CrawlerService", "CrawlerProcess", "Resume").Infof("%s is resumed", p.uri.Host)
+	p.limiter.setPaused(false)
+}
+
+// Stop finishes crawl gracefully, requests in flight are finished and result has pages crawled so far
+func (p *CrawlerProcess) Stop() {
+	p.mux.Lock()
+	if p.stopped || p.Completed {
+		p.mux.Unlock()
+		return
+	}
+	p.stopped = true
+	dequeue := p.dequeue
+	p.mux.Unlock()
+
+	// queued crawl is finished right away, its seed links are kept for checkpoint
+	if dequeue != nil && dequeue() {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "Stop").Infof("%s is stopped before start", p.uri.Host)
+		p.mux.Lock()
+		p.startedAt = time.Now()
+		p.interrupted = append(p.interrupted, p.seedLinks()...)
+		p.mux.Unlock()
+		p.finish()
+		return
+	}
+
+	grace := time.Duration(orDefault(p.crawlerService.conf.StopGraceSec, defaultStopGraceSec)) * time.Second
+	log.WithTrace("CrawlerService", "CrawlerProcess", "Stop").Infof("%s is stopping, requests in flight are aborted in %v", p.uri.Host, grace)
+	p.cancel()
+
+	timer := time.AfterFunc(grace, p.abort)
+	go func() {
+		<-p.done
+		timer.Stop()
+	}()
+}
+
+// SetWorkers changes count of concurrent requests of crawl, adaptive concurrency continues from it
+func (p *CrawlerProcess) SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	p.limiter.setWorkers(p.uri.Host, n)
+
+	// finished crawl does not get new workers, running or not started crawl starts them on demand
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.running == 0 {
+		return
+	}
+	for i := p.running; i < n; i++ {
+		p.runWorker()
+	}
+}
+
+// Stats returns progress of crawl, it is safe to call it anytime
+func (p *CrawlerProcess) Stats() CrawlerStats {
+	frontier := p.frontier.getStats()
+	limit, paused := p.limiter.getLimit()
+
+	p.mux.RLock()
+	stats := CrawlerStats{
+		Domain:   p.uri.Host,
+		Fetched:  p.requests,
+		Queued:   frontier.Length,
+		InFlight: frontier.InFlight,
+		Errors:   len(p.failures) + p.errorPages,
+		Workers:  limit,
+	}
+
+	switch {
+	case p.startedAt.IsZero():
+		stats.State = StateQueued
+	case p.Completed && p.stopped:
+		stats.State = StateStopped
+	case p.Completed:
+		stats.State = StateCompleted
+	case p.stopped:
+		stats.State = StateStopping
+	case paused:
+		stats.State = StatePaused
+	default:
+		stats.State = StateRunning
+	}
+
+	if !p.startedAt.IsZero() {
+		stats.Elapsed = time.Since(p.startedAt)
+		if p.Completed {
+			stats.Elapsed = p.finishedAt.Sub(p.startedAt)
+		}
+	}
+	p.mux.RUnlock()
+
+	stats.RequestsPerSec = p.RequestsPerSec()
+
+	return stats
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCrawlerProcessControl(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		time.Sleep(5 * time.Millisecond)
+		fmt.Fprintf(w, `<html><head><title>page</title></head><body><a href="/p%d">a</a><a href="/q%d">b</a></body></html>`, n, n)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &CrawlerService{
+		conf:       config.CrawlerConfig{Depth: 100, Workers: 2},
+		httpClient: srv.Client(),
+		scheduler:  newScheduler(0, 0),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	p, err := s.newCrawlerProcess(Seed{Url: srv.URL + "/"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := p.Stats().State; state != StateQueued {
+		t.Errorf("expected queued state, got %s", state)
+	}
+
+	s.scheduler.startSite(p.run)
+	time.Sleep(30 * time.Millisecond)
+
+	p.Pause()
+	time.Sleep(20 * time.Millisecond)
+	paused := p.Stats()
+	if paused.State != StatePaused || paused.Fetched == 0 {
+		t.Fatalf("unexpected stats of paused crawl: %+v", paused)
+	}
+	if paused.InFlight != 0 {
+		t.Errorf("paused crawl should not take links from frontier, %d in flight", paused.InFlight)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if fetched := p.Stats().Fetched; fetched != paused.Fetched {
+		t.Errorf("paused crawl should not fetch, fetched %d then %d", paused.Fetched, fetched)
+	}
+
+	p.SetWorkers(4)
+	p.Resume()
+	time.Sleep(30 * time.Millisecond)
+	running := p.Stats()
+	if running.State != StateRunning || running.Workers != 4 || running.Fetched <= paused.Fetched {
+		t.Errorf("unexpected stats of resumed crawl: %+v", running)
+	}
+
+	p.Stop()
+	res := p.GetResult()
+	if state := p.Stats().State; state != StateStopped {
+		t.Errorf("expected stopped state, got %s", state)
+	}
+	if res.InnerLinksCount == 0 {
+		t.Error("stopped crawl should have partial result")
+	}
+
+	// service context is not canceled by stop of one crawl
+	if s.ctx.Err() != nil {
+		t.Error("service context should not be canceled")
+	}
+}
+
+func TestCrawlerProcessContextsReleased(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><a href="/a">a</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	s := newTestCrawlerService(srv, config.CrawlerConfig{Depth: 2, Workers: 2})
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.GetResult()
+	if p.ctx.Err() == nil || p.requestCtx.Err() == nil {
+		t.Error("contexts of finished crawl should be canceled")
+	}
+	if s.ctx.Err() != nil {
+		t.Error("service context should not be canceled")
+	}
+}
+
+func TestCrawlerProcessStopQueued(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		fmt.Fprint(w, `<html><body></body></html>`)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	s := newTestCrawlerService(srv, config.CrawlerConfig{Depth: 2, Workers: 1})
+	s.scheduler = newScheduler(0, 1)
+	defer s.Close()
+
+	running, err := s.StartSeed(Seed{Url: srv.URL + "/slow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := s.StartSeed(Seed{Url: srv.URL + "/queued"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := queued.Stats().State; state != StateQueued {
+		t.Fatalf("second seed should be queued, got %s", state)
+	}
+
+	queued.Stop()
+	select {
+	case <-queued.done:
+	case <-time.After(time.Second):
+		t.Fatal("queued crawl should be finished right away by stop")
+	}
+	if state := queued.Stats().State; state != StateStopped || !queued.Interrupted() {
+		t.Errorf("queued crawl should be stopped and interrupted, got %s", state)
+	}
+	c, err := queued.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Queue) != 1 || c.Queue[0].Url != srv.URL+"/queued" {
+		t.Errorf("seed of queued crawl should be in checkpoint, got %+v", c.Queue)
+	}
+
+	s.scheduler.mux.Lock()
+	pending := len(s.scheduler.pending)
+	s.scheduler.mux.Unlock()
+	if pending != 0 {
+		t.Errorf("stopped crawl should be removed from scheduler queue, %d queued", pending)
+	}
+	if state := running.Stats().State; state != StateRunning {
+		t.Errorf("the first crawl should keep running, got %s", state)
+	}
+}
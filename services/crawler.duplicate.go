@@ -0,0 +1,236 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/config"
+	"go-link-crawler/utils"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultSimHashThreshold = 3
+	// fingerprint is split into bands, pages within threshold distance share at least one band
+	simHashBands = 4
+)
+
+type duplicateReport struct {
+	Exact        [][]string `json:"exact"` // pages with the same visible text
+	Near         [][]string `json:"near"`  // pages with similar visible text
+	SkippedPages []string   `json:"skipped_pages"`
+}
+
+type pageFingerprint struct {
+	Url     string
+	Hash    string
+	SimHash uint64
+}
+
+// duplicateIndex finds known pages with the same or similar content
+type duplicateIndex struct {
+	exact map[string]string // hash -> first url
+	bands [simHashBands]map[uint16][]pageFingerprint
+}
+
+func newDuplicateIndex() *duplicateIndex {
+	idx := &duplicateIndex{exact: make(map[string]string)}
+	for i := range idx.bands {
+		idx.bands[i] = make(map[uint16][]pageFingerprint)
+	}
+	return idx
+}
+
+func simHashBand(h uint64, band int) uint16 {
+	return uint16(h >> uint(band*16))
+}
+
+// add stores fingerprint and returns url of the page it duplicates
+func (idx *duplicateIndex) add(fp pageFingerprint, threshold int) string {
+	original := ""
+	if u, ok := idx.exact[fp.Hash]; ok {
+		original = u
+	} else {
+		idx.exact[fp.Hash] = fp.Url
+		if near := idx.near(fp.SimHash, threshold); len(near) > 0 {
+			original = near[0].Url
+		}
+	}
+
+	for i := range idx.bands {
+		b := simHashBand(fp.SimHash, i)
+		idx.bands[i][b] = append(idx.bands[i][b], fp)
+	}
+
+	return original
+}
+
+// near returns indexed fingerprints within threshold distance of h in order of bands,
+// bands find all of them because threshold is less than bands count
+func (idx *duplicateIndex) near(h uint64, threshold int) []pageFingerprint {
+	res := make([]pageFingerprint, 0)
+	seen := make(map[string]bool)
+	for i := range idx.bands {
+		for _, c := range idx.bands[i][simHashBand(h, i)] {
+			if !seen[c.Url] && utils.HammingDistance(c.SimHash, h) <= threshold {
+				seen[c.Url] = true
+				res = append(res, c)
+			}
+		}
+	}
+	return res
+}
+
+// checkDuplicatesConfig validates threshold of enabled detection, near-duplicates at distance of bands count
+// or more may share no band and would not be found
+func checkDuplicatesConfig(conf config.DuplicatesConfig) error {
+	if !conf.Enabled {
+		return nil
+	}
+	if conf.Threshold < 0 || conf.Threshold >= simHashBands {
+		return fmt.Errorf("invalid duplicates threshold %d, use 1 to %d", conf.Threshold, simHashBands-1)
+	}
+	return nil
+}
+
+func (p *CrawlerProcess) simHashThreshold() int {
+	if t := p.crawlerService.conf.Duplicates.Threshold; t > 0 {
+		return t
+	}
+	return defaultSimHashThreshold
+}
+
+// parseGoqueryText returns visible text of page
+func (p *CrawlerProcess) parseGoqueryText(body *goquery.Document) string {
+	s := body.Find("body").Clone()
+	s.Find("script, style, noscript, template, svg").Remove()
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+func getPageFingerprint(url, text string) pageFingerprint {
+	sum := sha1.Sum([]byte(text))
+	return pageFingerprint{
+		Url:     url,
+		Hash:    hex.EncodeToString(sum[:]),
+		SimHash: utils.SimHash(text),
+	}
+}
+
+// isContentDuplicate indexes page content and reports that it duplicates known page
+func (p *CrawlerProcess) isContentDuplicate(fp pageFingerprint) (string, bool) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	original := p.duplicates.add(fp, p.simHashThreshold())
+	if original == "" {
+		return "", false
+	}
+
+	if p.crawlerService.conf.Duplicates.SkipLinks {
+		p.skippedDuplicates = append(p.skippedDuplicates, fp.Url)
+	}
+
+	return original, true
+}
+
+// getDuplicateReport clusters pages by content hash and SimHash distance
+func (p *CrawlerProcess) getDuplicateReport() *duplicateReport {
+	if !p.crawlerService.conf.Duplicates.Enabled {
+		return nil
+	}
+
+	threshold := p.simHashThreshold()
+
+	p.mux.RLock()
+	fps := make([]pageFingerprint, 0, len(p.data))
+	for l, d := range p.data {
+		if d.ContentHash != "" {
+			fps = append(fps, pageFingerprint{Url: l, Hash: d.ContentHash, SimHash: d.SimHash})
+		}
+	}
+	skipped := append([]string{}, p.skippedDuplicates...)
+	p.mux.RUnlock()
+
+	sort.Slice(fps, func(i, j int) bool { return fps[i].Url < fps[j].Url })
+
+	res := &duplicateReport{
+		Exact:        [][]string{},
+		Near:         [][]string{},
+		SkippedPages: skipped,
+	}
+
+	// exact duplicates
+	byHash := make(map[string][]string)
+	for _, fp := range fps {
+		byHash[fp.Hash] = append(byHash[fp.Hash], fp.Url)
+	}
+	for _, urls := range byHash {
+		if len(urls) > 1 {
+			res.Exact = append(res.Exact, urls)
+		}
+	}
+
+	// near duplicates are clustered by union-find over unique hashes
+	unique := make([]pageFingerprint, 0, len(byHash))
+	for _, fp := range fps {
+		if byHash[fp.Hash][0] == fp.Url {
+			unique = append(unique, fp)
+		}
+	}
+
+	parent := make([]int, len(unique))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	index := make(map[string]int, len(unique)) // hash -> index in unique
+	for i, fp := range unique {
+		index[fp.Hash] = i
+	}
+	p.mux.RLock()
+	for i, fp := range unique {
+		for _, c := range p.duplicates.near(fp.SimHash, threshold) {
+			if j, ok := index[c.Hash]; ok && j != i {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+	p.mux.RUnlock()
+
+	clusters := make(map[int][]string)
+	hashes := make(map[int]int)
+	for i, fp := range unique {
+		root := find(i)
+		clusters[root] = append(clusters[root], byHash[fp.Hash]...)
+		hashes[root]++
+	}
+	for root, urls := range clusters {
+		if hashes[root] > 1 {
+			sort.Strings(urls)
+			res.Near = append(res.Near, urls)
+		}
+	}
+
+	sortClusters(res.Exact)
+	sortClusters(res.Near)
+	sort.Strings(res.SkippedPages)
+
+	return res
+}
+
+func sortClusters(clusters [][]string) {
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i][0] < clusters[j][0] })
+}
+
+func formatSimHash(h uint64) string {
+	return fmt.Sprintf("%016x", h)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"go-link-crawler/config"
+	"testing"
+)
+
+func TestDuplicateIndex(t *testing.T) {
+	idx := newDuplicateIndex()
+
+	if original := idx.add(pageFingerprint{Url: "/a", Hash: "1", SimHash: 0xff00ff00ff00ff00}, 3); original != "" {
+		t.Errorf("the first page should not be duplicate, got %s", original)
+	}
+	if original := idx.add(pageFingerprint{Url: "/b", Hash: "1", SimHash: 0xff00ff00ff00ff00}, 3); original != "/a" {
+		t.Errorf("page with the same hash should duplicate /a, got %q", original)
+	}
+	// 3 different bits in one band
+	if original := idx.add(pageFingerprint{Url: "/c", Hash: "2", SimHash: 0xff00ff00ff00ff07}, 3); original != "/a" {
+		t.Errorf("page within threshold should duplicate /a, got %q", original)
+	}
+	// 4 different bits spread over all bands
+	if original := idx.add(pageFingerprint{Url: "/d", Hash: "3", SimHash: 0xff01ff01ff01ff01}, 3); original != "" {
+		t.Errorf("page over threshold should not be duplicate, got %s", original)
+	}
+
+	near := idx.near(0xff00ff00ff00ff00, 3)
+	urls := make([]string, 0, len(near))
+	for _, fp := range near {
+		urls = append(urls, fp.Url)
+	}
+	if len(urls) != 3 || urls[0] != "/a" || urls[1] != "/b" || urls[2] != "/c" {
+		t.Errorf("expected near pages /a, /b, /c, got %v", urls)
+	}
+}
+
+func TestDuplicateReport(t *testing.T) {
+	conf := config.CrawlerConfig{}
+	conf.Duplicates.Enabled = true
+	p := &CrawlerProcess{
+		crawlerService: &CrawlerService{conf: conf},
+		duplicates:     newDuplicateIndex(),
+		data:           map[string]crawlerLinkData{},
+	}
+	for _, fp := range []pageFingerprint{
+		{Url: "/a", Hash: "1", SimHash: 0xff00ff00ff00ff00},
+		{Url: "/b", Hash: "1", SimHash: 0xff00ff00ff00ff00},
+		{Url: "/c", Hash: "2", SimHash: 0xff00ff00ff00ff07},
+		{Url: "/d", Hash: "3", SimHash: 0xff01ff01ff01ff01},
+		{Url: "/e", Hash: "4", SimHash: 0xff01ff01ff01ff03},
+	} {
+		p.isContentDuplicate(fp)
+		p.data[fp.Url] = crawlerLinkData{ContentHash: fp.Hash, SimHash: fp.SimHash}
+	}
+
+	res := p.getDuplicateReport()
+	if len(res.Exact) != 1 || len(res.Exact[0]) != 2 {
+		t.Errorf("expected exact cluster /a, /b, got %v", res.Exact)
+	}
+	if len(res.Near) != 2 || len(res.Near[0]) != 3 || len(res.Near[1]) != 2 || res.Near[1][0] != "/d" {
+		t.Errorf("expected near clusters /a, /b, /c and /d, /e, got %v", res.Near)
+	}
+}
+
+func TestCheckDuplicatesConfig(t *testing.T) {
+	for threshold, valid := range map[int]bool{-1: false, 0: true, 3: true, 4: false} {
+		err := checkDuplicatesConfig(config.DuplicatesConfig{Enabled: true, Threshold: threshold})
+		if (err == nil) != valid {
+			t.Errorf("threshold %d expected valid %v, got err: %v", threshold, valid, err)
+		}
+	}
+
+	if err := checkDuplicatesConfig(config.DuplicatesConfig{Threshold: 4}); err != nil {
+		t.Errorf("threshold of disabled duplicates should not be checked, got err: %v", err)
+	}
+}
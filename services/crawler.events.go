@@ -0,0 +1,183 @@
+package services
+
+import (
+	"go-link-crawler/log"
+	"sync"
+	"time"
+)
+
+// types of crawl events
+const (
+	EventPage     = "page"
+	EventError    = "error"
+	EventProgress = "progress"
+	EventDone     = "done"
+)
+
+// eventBuffer is size of channel of subscriber, events are dropped for slow subscriber
+// so crawl workers are never blocked
+const eventBuffer = 256
+
+// eventProgressInterval is minimal time between progress events
+const eventProgressInterval = time.Second
+
+// CrawlerEvent is live event of crawl process, page and error events are sent for every fetched link,
+// progress events with stats are sent at most once per second and done event is the last one
+type CrawlerEvent struct {
+	Type       string        `json:"type"`
+	Domain     string        `json:"domain"`
+	Time       time.Time     `json:"time"`
+	Url        string        `json:"url,omitempty"`
+	Depth      int           `json:"depth,omitempty"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Title      string        `json:"title,omitempty"`
+	DurationMs int64         `json:"duration_ms,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Stats      *CrawlerStats `json:"stats,omitempty"`
+}
+
+// eventBus sends events of crawl process to subscribers
+type eventBus struct {
+	subs         map[chan CrawlerEvent]bool
+	done         *CrawlerEvent // last event, bus is closed when it is set
+	lastProgress time.Time
+	dropped      int
+	mux          sync.Mutex
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[chan CrawlerEvent]bool)}
+}
+
+func (b *eventBus) subscribe() (<-chan CrawlerEvent, func()) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	ch := make(chan CrawlerEvent, eventBuffer)
+	if b.done != nil {
+		ch <- *b.done
+		close(ch)
+		return ch, func() {}
+	}
+	b.subs[ch] = true
+
+	return ch, func() {
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		if b.subs[ch] {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// active reports if there are subscribers, events are not built without them
+func (b *eventBus) active() bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return len(b.subs) > 0
+}
+
+func (b *eventBus) send(e CrawlerEvent) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			b.dropped++
+		}
+	}
+}
+
+// progressDue reports if progress event should be sent now
+func (b *eventBus) progressDue(now time.Time) bool {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if len(b.subs) == 0 || now.Sub(b.lastProgress) < eventProgressInterval {
+		return false
+	}
+	b.lastProgress = now
+	return true
+}
+
+// close sends done event and closes all subscribers, done event is kept for late subscribers
+func (b *eventBus) close(e CrawlerEvent) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.done != nil {
+		return
+	}
+	b.done = &e
+	for ch := range b.subs {
+		// done event is not dropped, space is made by the oldest event
+		select {
+		case ch <- e:
+		default:
+			<-ch
+			ch <- e
+		}
+		delete(b.subs, ch)
+		close(ch)
+	}
+	if b.dropped > 0 {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "eventBus").Warnf("%s: %d events were dropped for slow subscribers", e.Domain, b.dropped)
+	}
+}
+
+// Subscribe returns live events of crawl and func to unsubscribe, channel is closed after done event.
+// Subscriber of finished crawl gets only done event.
+func (p *CrawlerProcess) Subscribe() (<-chan CrawlerEvent, func()) {
+	return p.events.subscribe()
+}
+
+func (p *CrawlerProcess) newEvent(typ string, link crawlerLink) CrawlerEvent {
+	return CrawlerEvent{
+		Type:   typ,
+		Domain: p.uri.Host,
+		Time:   time.Now(),
+		Url:    link.Url,
+		Depth:  link.Depth,
+	}
+}
+
+// emitPage sends page event of fetched link and progress event when it is due
+func (p *CrawlerProcess) emitPage(link crawlerLink, d crawlerLinkData) {
+	if !p.events.active() {
+		return
+	}
+	e := p.newEvent(EventPage, link)
+	e.StatusCode = d.StatusCode
+	e.Title = d.Title
+	e.DurationMs = int64(d.Since / time.Millisecond)
+	p.events.send(e)
+
+	p.emitProgress()
+}
+
+// emitError sends error event of link which is not fetched or parsed
+func (p *CrawlerProcess) emitError(link crawlerLink, err error) {
+	if !p.events.active() {
+		return
+	}
+	e := p.newEvent(EventError, link)
+	e.Error = err.Error()
+	p.events.send(e)
+
+	p.emitProgress()
+}
+
+func (p *CrawlerProcess) emitProgress() {
+	now := time.Now()
+	if !p.events.progressDue(now) {
+		return
+	}
+	stats := p.Stats()
+	p.events.send(CrawlerEvent{Type: EventProgress, Domain: p.uri.Host, Time: now, Stats: &stats})
+}
+
+// emitDone sends final stats and closes subscribers, it is called when crawl is finished
+func (p *CrawlerProcess) emitDone() {
+	stats := p.Stats()
+	p.events.close(CrawlerEvent{Type: EventDone, Domain: p.uri.Host, Time: time.Now(), Stats: &stats})
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCrawlerProcessEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>page</title></head><body><a href="/a">a</a><a href="/broken">b</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := &CrawlerService{
+		conf:       config.CrawlerConfig{Depth: 3, Workers: 1},
+		httpClient: srv.Client(),
+		scheduler:  newScheduler(0, 0),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	p, err := s.newCrawlerProcess(Seed{Url: srv.URL + "/"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, unsubscribe := p.Subscribe()
+	defer unsubscribe()
+
+	s.scheduler.startSite(p.run)
+
+	counts := map[string]int{}
+	var last CrawlerEvent
+	for e := range events {
+		counts[e.Type]++
+		last = e
+		if e.Type == EventPage && (e.StatusCode != http.StatusOK || e.Title != "page") {
+			t.Errorf("unexpected page event: %+v", e)
+		}
+		if e.Type == EventError && (e.Url != srv.URL+"/broken" || e.Error == "") {
+			t.Errorf("unexpected error event: %+v", e)
+		}
+	}
+
+	if counts[EventPage] != 2 || counts[EventError] != 1 || counts[EventProgress] != 1 || counts[EventDone] != 1 {
+		t.Errorf("unexpected events: %v", counts)
+	}
+	if last.Type != EventDone || last.Stats.State != StateCompleted || last.Stats.Fetched != 2 {
+		t.Errorf("last event should be done with final stats, got %+v", last)
+	}
+
+	// subscriber of finished crawl gets only done event
+	events, _ = p.Subscribe()
+	if e, ok := <-events; !ok || e.Type != EventDone {
+		t.Errorf("expected done event, got %+v", e)
+	}
+	if _, ok := <-events; ok {
+		t.Error("events of finished crawl should be closed")
+	}
+}
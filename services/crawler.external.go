@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultExternalWorkers = 5
+	defaultExternalTimeout = 10 * time.Second
+	// body of GET fallback is not needed
+	externalMaxBodySize = 64 * 1024
+	// externalMinInterval bounds rate of checks, ticker cannot have zero interval
+	externalMinInterval = time.Millisecond
+)
+
+// externalCheck is status of external url, it is shared by all crawl processes
+type externalCheck struct {
+	Url        string
+	StatusCode int
+	Error      string
+	done       chan struct{}
+}
+
+func (c *externalCheck) isDead() bool {
+	return c.Error != "" || c.StatusCode >= http.StatusBadRequest
+}
+
+// externalChecker checks every unique external url once with own workers and rate limit
+type externalChecker struct {
+	conf    config.ExternalCheckConfig
+	client  *http.Client
+	ctx     context.Context
+	cache   map[string]*externalCheck
+	queue   []*externalCheck
+	cond    *sync.Cond
+	mux     sync.Mutex
+	limiter <-chan time.Time
+}
+
+func newExternalChecker(ctx context.Context, conf config.ExternalCheckConfig) *externalChecker {
+	timeout := defaultExternalTimeout
+	if conf.TimeoutSec > 0 {
+		timeout = time.Duration(conf.TimeoutSec) * time.Second
+	}
+
+	c := &externalChecker{
+		conf: conf,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+			Timeout:   timeout,
+		},
+		ctx:   ctx,
+		cache: make(map[string]*externalCheck),
+	}
+	c.cond = sync.NewCond(&c.mux)
+
+	var ticker *time.Ticker
+	if conf.RequestsPerSec > 0 {
+		interval := time.Duration(float64(time.Second) / conf.RequestsPerSec)
+		if interval < externalMinInterval {
+			interval = externalMinInterval
+		}
+		ticker = time.NewTicker(interval)
+		c.limiter = ticker.C
+	}
+
+	workers := conf.Workers
+	if workers <= 0 {
+		workers = defaultExternalWorkers
+	}
+	for i := 0; i < workers; i++ {
+		go c.runWorker()
+	}
+
+	// wake up workers to exit
+	go func() {
+		<-ctx.Done()
+		if ticker != nil {
+			ticker.Stop()
+		}
+		c.mux.Lock()
+		c.cond.Broadcast()
+		c.mux.Unlock()
+	}()
+
+	return c
+}
+
+// enqueue schedules check of url if it was not checked yet
+func (c *externalChecker) enqueue(url string) *externalCheck {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if check, ok := c.cache[url]; ok {
+		return check
+	}
+
+	check := &externalCheck{Url: url, done: make(chan struct{})}
+	c.cache[url] = check
+	c.queue = append(c.queue, check)
+	c.cond.Signal()
+
+	return check
+}
+
+func (c *externalChecker) runWorker() {
+	for {
+		c.mux.Lock()
+		for len(c.queue) == 0 && c.ctx.Err() == nil {
+			c.cond.Wait()
+		}
+		if c.ctx.Err() != nil {
+			c.mux.Unlock()
+			return
+		}
+		check := c.queue[0]
+		c.queue = c.queue[1:]
+		c.mux.Unlock()
+
+		if c.limiter != nil {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-c.limiter:
+			}
+		}
+
+		c.check(check)
+		close(check.done)
+	}
+}
+
+// check requests url by HEAD, GET is used if HEAD is failed or not allowed
+func (c *externalChecker) check(check *externalCheck) {
+	statusCode, err := c.request(http.MethodHead, check.Url)
+	if err != nil || statusCode >= http.StatusBadRequest {
+		statusCode, err = c.request(http.MethodGet, check.Url)
+	}
+
+	check.StatusCode = statusCode
+	if err != nil {
+		check.Error = err.Error()
+	}
+
+	log.WithTrace("CrawlerService", "externalChecker", "check").Tracef("external link: %s status: %d err: %v", check.Url, statusCode, err)
+}
+
+func (c *externalChecker) request(method, url string) (int, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := c.client.Do(req.WithContext(c.ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, externalMaxBodySize))
+
+	return res.StatusCode, nil
+}
+
+// checkExternalLink schedules check of external link found on page
+func (p *CrawlerProcess) checkExternalLink(url string) {
+	if p.crawlerService.externalChecker == nil {
+		return
+	}
+	p.crawlerService.externalChecker.enqueue(url)
+}
+
+// getDeadExternalLinks waits for checks of external links of process,
+// stopped crawl does not wait and has only finished checks
+func (p *CrawlerProcess) getDeadExternalLinks() []brokenLink {
+	checker := p.crawlerService.externalChecker
+	if checker == nil {
+		return nil
+	}
+
+	p.mux.RLock()
+	stopped := p.stopped
+	checks := make([]*externalCheck, 0, len(p.external))
+	for l := range p.external {
+		checks = append(checks, checker.enqueue(l))
+	}
+	p.mux.RUnlock()
+
+	res := make([]brokenLink, 0)
+	for _, check := range checks {
+		if stopped {
+			select {
+			case <-check.done:
+			default:
+				continue
+			}
+		} else {
+			select {
+			case <-checker.ctx.Done():
+				return res
+			case <-check.done:
+			}
+		}
+
+		if check.isDead() {
+			p.mux.RLock()
+			refs := append([]string{}, p.referrers[check.Url]...)
+			p.mux.RUnlock()
+			sort.Strings(refs)
+
+			res = append(res, brokenLink{
+				Url:        check.Url,
+				StatusCode: check.StatusCode,
+				Error:      check.Error,
+				Referrers:  refs,
+			})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Url < res[j].Url })
+
+	return res
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestExternalChecker(t *testing.T) {
+	var mux sync.Mutex
+	requests := make(map[string]int) // method and path -> count
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		mux.Unlock()
+
+		switch {
+		case r.URL.Path == "/no-head" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/gone":
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := newExternalChecker(ctx, config.ExternalCheckConfig{Workers: 2, RequestsPerSec: 1e12})
+
+	checks := []*externalCheck{
+		c.enqueue(srv.URL + "/ok"),
+		c.enqueue(srv.URL + "/no-head"),
+		c.enqueue(srv.URL + "/gone"),
+	}
+	if c.enqueue(srv.URL+"/ok") != checks[0] {
+		t.Error("url should be checked once")
+	}
+	for _, check := range checks {
+		<-check.done
+	}
+
+	if checks[0].isDead() || checks[1].isDead() || !checks[2].isDead() {
+		t.Errorf("only /gone should be dead, got %+v %+v %+v", checks[0], checks[1], checks[2])
+	}
+	if checks[1].StatusCode != http.StatusOK {
+		t.Errorf("GET should be used if HEAD is not allowed, got status %d", checks[1].StatusCode)
+	}
+
+	mux.Lock()
+	defer mux.Unlock()
+	expected := map[string]int{
+		"HEAD /ok":      1,
+		"HEAD /no-head": 1,
+		"GET /no-head":  1,
+		"HEAD /gone":    1,
+		"GET /gone":     1,
+	}
+	for r, count := range expected {
+		if requests[r] != count {
+			t.Errorf("%s expected %d requests, got %d", r, count, requests[r])
+		}
+	}
+	if requests["GET /ok"] != 0 {
+		t.Error("GET should not be used if HEAD succeeded")
+	}
+}
+
+func TestWithConfigExternalChecker(t *testing.T) {
+	conf := config.CrawlerConfig{}
+	conf.ExternalCheck.Enabled = true
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &CrawlerService{conf: conf, ctx: ctx, cancel: cancel, externalChecker: newExternalChecker(ctx, conf.ExternalCheck)}
+	defer s.Close()
+
+	jobConf := conf
+	jobConf.ExternalCheck.Workers = 1
+	job := s.WithConfig(jobConf)
+	if job.externalChecker == nil || job.externalChecker == s.externalChecker || job.externalChecker.conf.Workers != 1 {
+		t.Fatal("job should have own checker with its settings")
+	}
+
+	job.Close()
+	if job.externalChecker.ctx.Err() == nil {
+		t.Error("checker of job should be stopped by close")
+	}
+	if s.ctx.Err() != nil {
+		t.Error("service should not be closed by job")
+	}
+
+	jobConf.ExternalCheck.Enabled = false
+	if s.WithConfig(jobConf).externalChecker != nil {
+		t.Error("job without external check should have no checker")
+	}
+}
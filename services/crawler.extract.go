@@ -0,0 +1,123 @@
+package services
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"regexp"
+	"strings"
+)
+
+// extractor is compiled extraction rule of config
+type extractor struct {
+	rule     config.ExtractRule
+	selector cascadia.Selector
+	xpath    *xpath.Expr
+	re       *regexp.Regexp
+}
+
+// newExtractors compiles rules, invalid rules are logged and skipped
+func newExtractors(rules []config.ExtractRule) []*extractor {
+	res := make([]*extractor, 0, len(rules))
+	for _, r := range rules {
+		e := &extractor{rule: r}
+		var err error
+
+		switch {
+		case r.Name == "":
+			log.WithTrace("CrawlerService", "newExtractors").Errorf("extraction rule without name is skipped")
+			continue
+		case (r.Selector == "") == (r.XPath == ""):
+			log.WithTrace("CrawlerService", "newExtractors").Errorf("extraction rule %s needs either selector or xpath", r.Name)
+			continue
+		case r.XPath != "":
+			if e.xpath, err = xpath.Compile(r.XPath); err != nil {
+				log.WithTrace("CrawlerService", "newExtractors").Errorf("extraction rule %s xpath: %s err: %v", r.Name, r.XPath, err)
+				continue
+			}
+		default:
+			if e.selector, err = cascadia.Compile(r.Selector); err != nil {
+				log.WithTrace("CrawlerService", "newExtractors").Errorf("extraction rule %s selector: %s err: %v", r.Name, r.Selector, err)
+				continue
+			}
+		}
+
+		if r.Regex != "" {
+			if e.re, err = regexp.Compile(r.Regex); err != nil {
+				log.WithTrace("CrawlerService", "newExtractors").Errorf("extraction rule %s regex: %s err: %v", r.Name, r.Regex, err)
+				continue
+			}
+		}
+
+		res = append(res, e)
+	}
+	return res
+}
+
+// extract returns string or []string for multi value rule, nil if nothing is found
+func (e *extractor) extract(body *goquery.Document) interface{} {
+	values := make([]string, 0)
+	add := func(s *goquery.Selection) bool {
+		var value string
+		if e.rule.Attr != "" {
+			value = s.AttrOr(e.rule.Attr, "")
+		} else {
+			value = strings.Join(strings.Fields(s.Text()), " ")
+		}
+
+		if e.re != nil {
+			m := e.re.FindStringSubmatch(value)
+			switch {
+			case m == nil:
+				value = ""
+			case len(m) > 1:
+				value = m[1]
+			default:
+				value = m[0]
+			}
+		}
+
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+		// single value rule stops on the first value
+		return e.rule.Multi || len(values) == 0
+	}
+
+	if e.xpath != nil {
+		for _, n := range body.Nodes {
+			for _, found := range htmlquery.QuerySelectorAll(n, e.xpath) {
+				if !add(goquery.NewDocumentFromNode(found).Selection) {
+					break
+				}
+			}
+		}
+	} else {
+		body.FindMatcher(e.selector).EachWithBreak(func(i int, s *goquery.Selection) bool {
+			return add(s)
+		})
+	}
+
+	switch {
+	case len(values) == 0:
+		return nil
+	case e.rule.Multi:
+		return values
+	default:
+		return values[0]
+	}
+}
+
+// parseGoqueryExtract evaluates extraction rules of config on page
+func (p *CrawlerProcess) parseGoqueryExtract(body *goquery.Document) map[string]interface{} {
+	res := make(map[string]interface{})
+	for _, e := range p.crawlerService.extractors {
+		if value := e.extract(body); value != nil {
+			res[e.rule.Name] = value
+		}
+	}
+	return res
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractors(t *testing.T) {
+	html := `<html><head><meta property="article:published_time" content="2019-10-01T10:00:00Z"></head><body>
+		<ol class="breadcrumb"><li><a href="/">Home</a></li><li><a href="/shoes">Shoes</a></li></ol>
+		<span itemprop="price" content="19.90">$19.90</span>
+		<p class="author">by  John
+			Smith</p>
+	</body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extractors := newExtractors([]config.ExtractRule{
+		{Name: "price", Selector: "[itemprop=price]", Attr: "content"},
+		{Name: "author", Selector: ".author", Regex: `^by (.+)$`},
+		{Name: "published", XPath: "//meta[@property='article:published_time']/@content", Regex: `^\d{4}-\d{2}-\d{2}`},
+		{Name: "breadcrumbs", XPath: "//ol[@class='breadcrumb']//a", Multi: true},
+		{Name: "missing", Selector: ".missing"},
+		{Name: "invalid", XPath: "//["},
+		{Name: "invalid-selector", Selector: "div["},
+	})
+	if len(extractors) != 5 {
+		t.Fatalf("invalid rules should be skipped, got %d extractors", len(extractors))
+	}
+
+	expected := map[string]interface{}{
+		"price":       "19.90",
+		"author":      "John Smith",
+		"published":   "2019-10-01",
+		"breadcrumbs": []string{"Home", "Shoes"},
+	}
+	p := &CrawlerProcess{crawlerService: &CrawlerService{extractors: extractors}}
+	if res := p.parseGoqueryExtract(doc); !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v, got %v", expected, res)
+	}
+}
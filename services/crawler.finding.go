@@ -0,0 +1,30 @@
+package services
+
+import "sort"
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityNotice  = "notice"
+)
+
+// crawlerFinding is a problem found by page checks
+type crawlerFinding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Url      string `json:"url"`
+	Message  string `json:"message"`
+}
+
+// sortFindings orders findings by url, check name and message
+func sortFindings(findings []crawlerFinding) {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Url != findings[j].Url {
+			return findings[i].Url < findings[j].Url
+		}
+		if findings[i].Check != findings[j].Check {
+			return findings[i].Check < findings[j].Check
+		}
+		return findings[i].Message < findings[j].Message
+	})
+}
@@ -0,0 +1,21 @@
+package services
+
+import "testing"
+
+func TestSortFindings(t *testing.T) {
+	findings := []crawlerFinding{
+		{Check: "b", Url: "/a", Message: "2"},
+		{Check: "b", Url: "/a", Message: "1"},
+		{Check: "a", Url: "/b", Message: "1"},
+		{Check: "a", Url: "/a", Message: "3"},
+	}
+	sortFindings(findings)
+
+	order := ""
+	for _, f := range findings {
+		order += f.Url + f.Check + f.Message + " "
+	}
+	if order != "/aa3 /ab1 /ab2 /ba1 " {
+		t.Errorf("findings should be ordered by url, check and message, got %s", order)
+	}
+}
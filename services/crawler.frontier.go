@@ -0,0 +1,284 @@
+package services
+
+import (
+	"container/heap"
+	"errors"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	FrontierBfs      = "bfs"
+	FrontierDfs      = "dfs"
+	FrontierPriority = "priority"
+
+	defaultFrontierStrategy = FrontierBfs
+)
+
+var (
+	errFrontierFull   = errors.New("frontier is full")
+	errFrontierClosed = errors.New("frontier is closed")
+)
+
+// FrontierScore returns priority of url for priority strategy, links with higher score are crawled first
+type FrontierScore func(url string, depth int, listed bool) float64
+
+// DefaultFrontierScore prefers urls listed in sitemap.xml, short paths and urls without query
+func DefaultFrontierScore(rawUrl string, depth int, listed bool) float64 {
+	score := 0.0
+	if listed {
+		score += 10
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return score
+	}
+	score -= float64(strings.Count(strings.Trim(u.Path, "/"), "/"))
+	if u.Path != "" && u.Path != "/" {
+		score--
+	}
+	if u.RawQuery != "" {
+		score--
+	}
+	return score
+}
+
+// frontierBoost is compiled boost rule of config
+type frontierBoost struct {
+	re    *regexp.Regexp
+	score float64
+}
+
+// newFrontierBoosts compiles boost rules, invalid rules are logged and skipped
+func newFrontierBoosts(rules []config.FrontierBoost) []frontierBoost {
+	res := make([]frontierBoost, 0, len(rules))
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			log.WithTrace("CrawlerService", "newFrontierBoosts").Errorf("frontier boost pattern: %s err: %v", r.Pattern, err)
+			continue
+		}
+		res = append(res, frontierBoost{re: re, score: r.Score})
+	}
+	return res
+}
+
+// boostFrontierScore adds scores of matching boost rules to score
+func boostFrontierScore(score FrontierScore, boosts []frontierBoost) FrontierScore {
+	if len(boosts) == 0 {
+		return score
+	}
+	return func(rawUrl string, depth int, listed bool) float64 {
+		res := score(rawUrl, depth, listed)
+		for _, b := range boosts {
+			if b.re.MatchString(rawUrl) {
+				res += b.score
+			}
+		}
+		return res
+	}
+}
+
+// frontierStats are metrics of frontier queue
+type frontierStats struct {
+	Strategy  string `json:"strategy"`
+	Length    int    `json:"length"`
+	MaxLength int    `json:"max_length"`
+	Pushed    int    `json:"pushed"`
+	Popped    int    `json:"popped"`
+	InFlight  int    `json:"in_flight"`
+	// Dropped links did not fit in max size
+	Dropped int `json:"dropped"`
+	// DroppedUrls are set in result only
+	DroppedUrls []string `json:"dropped_urls,omitempty"`
+}
+
+type frontierItem struct {
+	link  crawlerLink
+	score float64
+	seq   int
+}
+
+// frontierQueue is heap ordered by strategy
+type frontierQueue struct {
+	items    []frontierItem
+	strategy string
+}
+
+func (q *frontierQueue) Len() int { return len(q.items) }
+
+func (q *frontierQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	switch q.strategy {
+	case FrontierDfs:
+		return a.seq > b.seq
+	case FrontierPriority:
+		if a.score != b.score {
+			return a.score > b.score
+		}
+	default:
+		if a.link.Depth != b.link.Depth {
+			return a.link.Depth < b.link.Depth
+		}
+	}
+	return a.seq < b.seq
+}
+
+func (q *frontierQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *frontierQueue) Push(x interface{}) { q.items = append(q.items, x.(frontierItem)) }
+
+func (q *frontierQueue) Pop() interface{} {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
+
+// frontier is queue of links of crawl process, it is finished when it is empty and no link is processed
+type frontier struct {
+	queue    frontierQueue
+	score    FrontierScore
+	maxSize  int
+	seq      int
+	inFlight int
+	closed   bool
+	stats    frontierStats
+	cond     *sync.Cond
+	mux      sync.Mutex
+}
+
+func newFrontier(strategy string, maxSize int, score FrontierScore) *frontier {
+	switch strategy {
+	case FrontierBfs, FrontierDfs, FrontierPriority:
+	default:
+		strategy = defaultFrontierStrategy
+	}
+	if score == nil {
+		score = DefaultFrontierScore
+	}
+
+	f := &frontier{
+		queue:   frontierQueue{strategy: strategy},
+		score:   score,
+		maxSize: maxSize,
+		stats:   frontierStats{Strategy: strategy},
+	}
+	f.cond = sync.NewCond(&f.mux)
+	return f
+}
+
+// push queues link, it returns errFrontierFull or errFrontierClosed if link is not queued
+func (f *frontier) push(link crawlerLink, listed bool) error {
+	score := 0.0
+	if f.queue.strategy == FrontierPriority {
+		score = f.score(link.Url, link.Depth, listed)
+	}
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.closed {
+		return errFrontierClosed
+	}
+	if f.maxSize > 0 && f.queue.Len() >= f.maxSize {
+		f.stats.Dropped++
+		return errFrontierFull
+	}
+
+	f.seq++
+	heap.Push(&f.queue, frontierItem{link: link, score: score, seq: f.seq})
+	f.stats.Pushed++
+	if f.queue.Len() > f.stats.MaxLength {
+		f.stats.MaxLength = f.queue.Len()
+	}
+	f.cond.Signal()
+
+	return nil
+}
+
+// pop waits for the next link, it returns false when crawl is finished,
+// every popped link has to be marked by done after processing
+func (f *frontier) pop() (crawlerLink, bool) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for f.queue.Len() == 0 && f.inFlight > 0 && !f.closed {
+		f.cond.Wait()
+	}
+	if f.queue.Len() == 0 || f.closed {
+		f.closed = true
+		f.cond.Broadcast()
+		return crawlerLink{}, false
+	}
+
+	item := heap.Pop(&f.queue).(frontierItem)
+	f.inFlight++
+	f.stats.Popped++
+
+	return item.link, true
+}
+
+// done marks popped link as processed, links found on it have to be pushed before
+func (f *frontier) done() {
+	f.mux.Lock()
+	f.inFlight--
+	if f.inFlight == 0 && f.queue.Len() == 0 {
+		f.cond.Broadcast()
+	}
+	f.mux.Unlock()
+}
+
+// close stops crawl, queued links are not returned anymore
+func (f *frontier) close() {
+	f.mux.Lock()
+	f.closed = true
+	f.cond.Broadcast()
+	f.mux.Unlock()
+}
+
+func (f *frontier) getStats() frontierStats {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	stats := f.stats
+	stats.Length = f.queue.Len()
+	stats.InFlight = f.inFlight
+	return stats
+}
+
+// queued returns links in queue, they are not ordered
+func (f *frontier) queued() []crawlerLink {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	res := make([]crawlerLink, 0, f.queue.Len())
+	for _, item := range f.queue.items {
+		res = append(res, item.link)
+	}
+	return res
+}
+
+// getDroppedUrls returns unique links which did not fit in frontier and were not queued later
+func (p *CrawlerProcess) getDroppedUrls() []string {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	res := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, l := range p.dropped {
+		if seen[l] || (!p.bounded() && p.isVisited(l)) {
+			continue
+		}
+		seen[l] = true
+		res = append(res, l)
+	}
+	sort.Strings(res)
+
+	return res
+}
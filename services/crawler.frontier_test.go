@@ -0,0 +1,117 @@
+package services
+
+import (
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFrontierOrder(t *testing.T) {
+	links := []crawlerLink{
+		{Url: "http://example.com/a/b/c", Depth: 2},
+		{Url: "http://example.com/a", Depth: 1},
+		{Url: "http://example.com/a?page=2", Depth: 1},
+		{Url: "http://example.com/listed/x/y", Depth: 2},
+	}
+	expected := map[string]string{
+		FrontierBfs:      "/a /a?page=2 /a/b/c /listed/x/y",
+		FrontierDfs:      "/listed/x/y /a?page=2 /a /a/b/c",
+		FrontierPriority: "/listed/x/y /a /a?page=2 /a/b/c",
+	}
+
+	for strategy, order := range expected {
+		f := newFrontier(strategy, 0, nil)
+		for _, l := range links {
+			f.push(l, strings.Contains(l.Url, "listed"))
+		}
+
+		urls := make([]string, 0)
+		for {
+			l, ok := f.pop()
+			if !ok {
+				break
+			}
+			urls = append(urls, strings.TrimPrefix(l.Url, "http://example.com"))
+			f.done()
+		}
+		if strings.Join(urls, " ") != order {
+			t.Errorf("%s: expected order %s, got %s", strategy, order, strings.Join(urls, " "))
+		}
+	}
+}
+
+func TestFrontierMaxSize(t *testing.T) {
+	f := newFrontier(FrontierBfs, 2, nil)
+	for _, u := range []string{"a", "b", "c"} {
+		f.push(crawlerLink{Url: u}, false)
+	}
+
+	l, _ := f.pop()
+	if err := f.push(crawlerLink{Url: "d", Depth: 1}, false); err != nil {
+		t.Errorf("link should fit after pop, got %v", err)
+	}
+	if err := f.push(crawlerLink{Url: "e", Depth: 1}, false); err != errFrontierFull {
+		t.Errorf("link should not fit in full frontier, got %v", err)
+	}
+	f.done()
+
+	stats := f.getStats()
+	if l.Url != "a" || stats.Dropped != 2 || stats.MaxLength != 2 || stats.Length != 2 || stats.Pushed != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	f.close()
+	if _, ok := f.pop(); ok {
+		t.Errorf("closed frontier should not return links")
+	}
+	if err := f.push(crawlerLink{Url: "f"}, false); err != errFrontierClosed {
+		t.Errorf("closed frontier should not queue links, got %v", err)
+	}
+}
+
+func TestFrontierBoost(t *testing.T) {
+	boosts := newFrontierBoosts([]config.FrontierBoost{
+		{Pattern: `/products/`, Score: 20},
+		{Pattern: `\?page=`, Score: -5},
+		{Pattern: `(`, Score: 100},
+	})
+	if len(boosts) != 2 {
+		t.Fatalf("invalid pattern should be skipped, got %d boosts", len(boosts))
+	}
+
+	score := boostFrontierScore(DefaultFrontierScore, boosts)
+	if score("http://example.com/products/a/b", 3, false) <= score("http://example.com/about", 1, true) {
+		t.Error("boosted url should have the highest score")
+	}
+	if score("http://example.com/list?page=2", 1, false) != DefaultFrontierScore("http://example.com/list?page=2", 1, false)-5 {
+		t.Error("negative boost should lower score")
+	}
+}
+
+func TestFrontierDroppedUrls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	conf := config.CrawlerConfig{Depth: 3, Workers: 1}
+	conf.Frontier.MaxSize = 1
+	s := newTestCrawlerService(srv, conf)
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+
+	expected := []string{srv.URL + "/b", srv.URL + "/c"}
+	if strings.Join(res.Frontier.DroppedUrls, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected dropped urls %v, got %v", expected, res.Frontier.DroppedUrls)
+	}
+}
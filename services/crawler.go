@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/tls"
 	"go-link-crawler/config"
-	"go-link-crawler/log"
 	"net/http"
 	"regexp"
 	"sync"
@@ -18,11 +17,16 @@ var (
 )
 
 type CrawlerService struct {
-	conf       config.CrawlerConfig
-	httpClient *http.Client
-	ctx        context.Context
-	cancel     context.CancelFunc
-	mux        sync.RWMutex
+	conf            config.CrawlerConfig
+	httpClient      *http.Client
+	externalChecker *externalChecker
+	extractors      []*extractor
+	scheduler       *scheduler
+	frontierScore   FrontierScore
+	frontierBoosts  []frontierBoost
+	ctx             context.Context
+	cancel          context.CancelFunc
+	mux             sync.RWMutex
 }
 
 // NewCrawlerService returns only first created instance
@@ -39,37 +43,74 @@ func NewCrawlerService(conf config.CrawlerConfig) *CrawlerService {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	crawlerServiceInstance = &CrawlerService{
-		conf:       conf,
-		httpClient: client,
-		ctx:        ctx,
-		cancel:     cancel,
+		conf:           conf,
+		httpClient:     client,
+		extractors:     newExtractors(conf.Extract),
+		scheduler:      newScheduler(conf.Scheduler.Workers, conf.Scheduler.MaxActiveSites),
+		frontierBoosts: newFrontierBoosts(conf.Frontier.Boost),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	if conf.ExternalCheck.Enabled {
+		crawlerServiceInstance.externalChecker = newExternalChecker(ctx, conf.ExternalCheck)
 	}
 
 	return crawlerServiceInstance
 }
 
+// WithConfig returns service with own config for crawls of a job, it shares http client and
+// global scheduler of s, its context and external checker are own, it has to be closed
+// when crawls of the job are finished
+func (s *CrawlerService) WithConfig(conf config.CrawlerConfig) *CrawlerService {
+	s.mux.RLock()
+	score := s.frontierScore
+	s.mux.RUnlock()
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	res := &CrawlerService{
+		conf:           conf,
+		httpClient:     s.httpClient,
+		extractors:     newExtractors(conf.Extract),
+		scheduler:      s.scheduler,
+		frontierScore:  score,
+		frontierBoosts: newFrontierBoosts(conf.Frontier.Boost),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	if conf.ExternalCheck.Enabled {
+		res.externalChecker = newExternalChecker(ctx, conf.ExternalCheck)
+	}
+
+	return res
+}
+
 func (s *CrawlerService) Close() {
 	s.cancel()
 }
 
+// SetFrontierScore sets score of links for priority frontier strategy of next crawls,
+// boost rules of config are added to it
+func (s *CrawlerService) SetFrontierScore(score FrontierScore) {
+	s.mux.Lock()
+	s.frontierScore = score
+	s.mux.Unlock()
+}
+
 func (s *CrawlerService) Start(rawUrl string) (*CrawlerProcess, error) {
-	p, err := s.newCrawlerProcess(rawUrl)
+	return s.StartSeed(Seed{Url: rawUrl})
+}
+
+// StartSeed starts crawl of seed with its depth, scope and tags,
+// crawl is queued if there are too many active sites
+func (s *CrawlerService) StartSeed(seed Seed) (*CrawlerProcess, error) {
+	p, err := s.newCrawlerProcess(seed, "")
 	if err != nil {
 		return p, err
 	}
 
-	// worker pools
-	for i := 0; i < s.conf.Workers; i++ {
-		p.runWorker()
-	}
-
-	// put the first link
-	log.WithTrace("CrawlerService", "Start").Trace("crawl link: ", rawUrl)
-	p.linksCount = 1
-	p.links <- crawlerLink{
-		Url:   rawUrl,
-		Depth: 0,
-	}
+	p.schedule()
 
 	return p, nil
 }
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// crawlerEdge is directed link between two pages
+type crawlerEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Text   string `json:"text,omitempty"`
+	Rel    string `json:"rel,omitempty"`
+}
+
+type graphNode struct {
+	Id       string `json:"id"`
+	Title    string `json:"title,omitempty"`
+	Depth    int    `json:"depth"` // -1 if page was not reached by crawler
+	External bool   `json:"external"`
+}
+
+type linkGraph struct {
+	Nodes []graphNode   `json:"nodes"`
+	Edges []crawlerEdge `json:"edges"`
+}
+
+func (p *CrawlerProcess) keepGraph() bool {
+	return p.crawlerService.conf.Graph.Enabled || p.crawlerService.conf.Analysis.Enabled
+}
+
+func (p *CrawlerProcess) addEdge(source, target string, href crawlerHref) {
+	if !p.keepGraph() || target == "" {
+		return
+	}
+
+	p.mux.Lock()
+	p.edges[crawlerEdge{
+		Source: source,
+		Target: target,
+		Text:   href.Text,
+		Rel:    href.Rel,
+	}] = true
+	p.mux.Unlock()
+}
+
+func (p *CrawlerProcess) getGraph() *linkGraph {
+	if !p.crawlerService.conf.Graph.Enabled {
+		return nil
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	g := &linkGraph{
+		Nodes: []graphNode{},
+		Edges: make([]crawlerEdge, 0, len(p.edges)),
+	}
+
+	nodes := make(map[string]bool)
+	addNode := func(id string) {
+		if nodes[id] {
+			return
+		}
+		nodes[id] = true
+
+		n := graphNode{Id: id, Depth: -1, External: p.external[id]}
+		if d, ok := p.sitemap[id]; ok {
+			n.Depth = d
+		}
+		if d, ok := p.data[id]; ok {
+			n.Title = d.Title
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+
+	for e := range p.edges {
+		addNode(e.Source)
+		addNode(e.Target)
+		g.Edges = append(g.Edges, e)
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].Id < g.Nodes[j].Id })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Source != g.Edges[j].Source {
+			return g.Edges[i].Source < g.Edges[j].Source
+		}
+		if g.Edges[i].Target != g.Edges[j].Target {
+			return g.Edges[i].Target < g.Edges[j].Target
+		}
+		return g.Edges[i].Text < g.Edges[j].Text
+	})
+
+	return g
+}
+
+// GraphFormatExt returns file extension for export format
+func GraphFormatExt(format string) string {
+	if format == "dot" {
+		return "gv"
+	}
+	return format
+}
+
+// Export writes graph in one of formats: dot, gexf, json
+func (g *linkGraph) Export(format string, w io.Writer) error {
+	switch format {
+	case "dot":
+		return g.WriteDOT(w)
+	case "gexf":
+		return g.WriteGEXF(w)
+	case "json":
+		return g.WriteJSON(w)
+	}
+	return fmt.Errorf("unknown graph format: %s", format)
+}
+
+// WriteDOT writes graph in GraphViz format
+func (g *linkGraph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph links {")
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(n.Id)}
+		if n.Title != "" {
+			attrs = append(attrs, "tooltip="+dotQuote(n.Title))
+		}
+		if n.External {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(n.Id), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		// rel is not an attribute of GraphViz, so it is a part of label
+		label := e.Text
+		if e.Rel != "" {
+			label = strings.TrimSpace(label + " (rel=" + e.Rel + ")")
+		}
+		fmt.Fprintf(bw, "  %s -> %s", dotQuote(e.Source), dotQuote(e.Target))
+		if label != "" {
+			fmt.Fprintf(bw, " [label=%s]", dotQuote(label))
+		}
+		fmt.Fprintln(bw, ";")
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	Mode            string           `xml:"mode,attr"`
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	Id    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	Id        string         `xml:"id,attr"`
+	Label     string         `xml:"label,attr"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	Id        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr"`
+	Target    string         `xml:"target,attr"`
+	Label     string         `xml:"label,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+// WriteGEXF writes graph in Gephi format
+func (g *linkGraph) WriteGEXF(w io.Writer) error {
+	doc := gexfDocument{
+		Xmlns:   "http://www.gexf.net/1.2draft",
+		Version: "1.2",
+		Graph: gexfGraph{
+			Mode:            "static",
+			DefaultEdgeType: "directed",
+			Attributes: []gexfAttributes{
+				{Class: "node", Attributes: []gexfAttribute{
+					{Id: "title", Title: "title", Type: "string"},
+					{Id: "depth", Title: "depth", Type: "integer"},
+					{Id: "external", Title: "external", Type: "boolean"},
+				}},
+				{Class: "edge", Attributes: []gexfAttribute{
+					{Id: "rel", Title: "rel", Type: "string"},
+				}},
+			},
+		},
+	}
+
+	for _, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{
+			Id:    n.Id,
+			Label: n.Id,
+			AttValues: []gexfAttValue{
+				{For: "title", Value: n.Title},
+				{For: "depth", Value: strconv.Itoa(n.Depth)},
+				{For: "external", Value: strconv.FormatBool(n.External)},
+			},
+		})
+	}
+
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, gexfEdge{
+			Id:        strconv.Itoa(i),
+			Source:    e.Source,
+			Target:    e.Target,
+			Label:     e.Text,
+			AttValues: []gexfAttValue{{For: "rel", Value: e.Rel}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+// WriteJSON writes graph as nodes and edges lists
+func (g *linkGraph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
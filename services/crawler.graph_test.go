@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func testLinkGraph() *linkGraph {
+	return &linkGraph{
+		Nodes: []graphNode{
+			{Id: "https://site.com/", Title: `Home "page"`, Depth: 0},
+			{Id: "https://site.com/a", Depth: 1},
+			{Id: "https://ext.com/", Depth: -1, External: true},
+		},
+		Edges: []crawlerEdge{
+			{Source: "https://site.com/", Target: "https://site.com/a", Text: "A"},
+			{Source: "https://site.com/", Target: "https://ext.com/", Text: "Ext", Rel: "nofollow"},
+			{Source: "https://site.com/a", Target: "https://ext.com/", Rel: "nofollow"},
+		},
+	}
+}
+
+func TestLinkGraphDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testLinkGraph().Export("dot", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dot := buf.String()
+	expected := []string{
+		"digraph links {\n",
+		`  "https://site.com/" [label="https://site.com/", tooltip="Home \"page\""];`,
+		`  "https://ext.com/" [label="https://ext.com/", style=dashed];`,
+		`  "https://site.com/" -> "https://site.com/a" [label="A"];`,
+		`  "https://site.com/" -> "https://ext.com/" [label="Ext (rel=nofollow)"];`,
+		`  "https://site.com/a" -> "https://ext.com/" [label="(rel=nofollow)"];`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(dot, line) {
+			t.Errorf("dot should contain %s, got:\n%s", line, dot)
+		}
+	}
+	if strings.Contains(dot, "rel=\"") {
+		t.Errorf("dot should not have rel attribute, got:\n%s", dot)
+	}
+}
+
+func TestLinkGraphGEXF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testLinkGraph().Export("gexf", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := gexfDocument{}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 3 {
+		t.Fatalf("expected 3 nodes and 3 edges, got %+v", doc.Graph)
+	}
+	n := doc.Graph.Nodes[2]
+	if n.Id != "https://ext.com/" || n.AttValues[1].Value != "-1" || n.AttValues[2].Value != "true" {
+		t.Errorf("unexpected external node %+v", n)
+	}
+	e := doc.Graph.Edges[1]
+	if e.Label != "Ext" || e.AttValues[0].Value != "nofollow" {
+		t.Errorf("unexpected nofollow edge %+v", e)
+	}
+}
+
+func TestLinkGraphJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testLinkGraph().Export("json", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	g := linkGraph{}
+	if err := json.Unmarshal(buf.Bytes(), &g); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 3 || len(g.Edges) != 3 || g.Edges[1].Rel != "nofollow" || !g.Nodes[2].External {
+		t.Errorf("unexpected graph %+v", g)
+	}
+
+	if err := testLinkGraph().Export("svg", &buf); err == nil {
+		t.Error("unknown format should be an error")
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hsts shorter than half a year is not accepted by preload lists
+const minHstsMaxAge = 180 * 24 * 60 * 60
+
+const (
+	headerHstsMissing          = "hsts-missing"
+	headerHstsShortMaxAge      = "hsts-short-max-age"
+	headerCspMissing           = "csp-missing"
+	headerCspUnsafe            = "csp-unsafe-inline-or-eval"
+	headerNosniffMissing       = "x-content-type-options-missing"
+	headerFrameOptionsMissing  = "x-frame-options-missing"
+	headerReferrerMissing      = "referrer-policy-missing"
+	headerReferrerUnsafe       = "referrer-policy-unsafe-url"
+	headerCookieNoSecure       = "cookie-without-secure"
+	headerCookieNoHttpOnly     = "cookie-without-httponly"
+	headerCookieNoSameSite     = "cookie-without-samesite"
+	headerCookieSameSiteNoneNS = "cookie-samesite-none-without-secure"
+)
+
+// headersReport is summary of security headers per host and pages which differ from their host
+type headersReport struct {
+	Hosts      []hostHeaders     `json:"hosts"`
+	Deviations []headerDeviation `json:"deviations"`
+}
+
+type hostHeaders struct {
+	Host  string `json:"host"`
+	Pages int    `json:"pages"`
+	// Issues of most of pages of host
+	Issues []string `json:"issues"`
+	// IssueCounts is count of pages per issue
+	IssueCounts map[string]int `json:"issue_counts"`
+}
+
+type headerDeviation struct {
+	Url string `json:"url"`
+	// Extra issues which are not common for host
+	Extra []string `json:"extra,omitempty"`
+	// Fixed common issues of host which page does not have
+	Fixed []string `json:"fixed,omitempty"`
+}
+
+// checkSecurityHeaders remembers issues of security headers and cookies of html response
+func (p *CrawlerProcess) checkSecurityHeaders(res crawlerResponse) {
+	if !p.crawlerService.conf.Security.Headers {
+		return
+	}
+	contentType := res.Header.Get("Content-Type")
+	if contentType != "" && !strings.Contains(strings.ToLower(contentType), "html") {
+		return
+	}
+
+	issues := securityHeaderIssues(res.Url, res.Header)
+
+	p.mux.Lock()
+	p.headerIssues[res.Url] = issues
+	p.mux.Unlock()
+}
+
+// securityHeaderIssues returns sorted unique issues of response headers
+func securityHeaderIssues(pageUrl string, header http.Header) []string {
+	set := make(map[string]bool)
+	https := strings.HasPrefix(pageUrl, "https://")
+
+	// browsers ignore hsts sent over http
+	if https {
+		hsts := strings.ToLower(header.Get("Strict-Transport-Security"))
+		if hsts == "" {
+			set[headerHstsMissing] = true
+		} else if hstsMaxAge(hsts) < minHstsMaxAge {
+			set[headerHstsShortMaxAge] = true
+		}
+	}
+
+	csp := strings.ToLower(strings.Join(header["Content-Security-Policy"], ";"))
+	if csp == "" {
+		set[headerCspMissing] = true
+	} else if strings.Contains(csp, "'unsafe-inline'") || strings.Contains(csp, "'unsafe-eval'") {
+		set[headerCspUnsafe] = true
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(header.Get("X-Content-Type-Options")), "nosniff") {
+		set[headerNosniffMissing] = true
+	}
+
+	// frame-ancestors of csp replaces x-frame-options
+	if header.Get("X-Frame-Options") == "" && !strings.Contains(csp, "frame-ancestors") {
+		set[headerFrameOptionsMissing] = true
+	}
+
+	referrer := strings.ToLower(header.Get("Referrer-Policy"))
+	if referrer == "" {
+		set[headerReferrerMissing] = true
+	} else if strings.Contains(referrer, "unsafe-url") {
+		set[headerReferrerUnsafe] = true
+	}
+
+	for _, c := range (&http.Response{Header: header}).Cookies() {
+		if https && !c.Secure {
+			set[headerCookieNoSecure+" "+c.Name] = true
+		}
+		if !c.HttpOnly {
+			set[headerCookieNoHttpOnly+" "+c.Name] = true
+		}
+		switch {
+		// zero value is attribute absence, default mode is attribute without value
+		case c.SameSite == 0 || c.SameSite == http.SameSiteDefaultMode:
+			set[headerCookieNoSameSite+" "+c.Name] = true
+		case c.SameSite == http.SameSiteNoneMode && !c.Secure:
+			set[headerCookieSameSiteNoneNS+" "+c.Name] = true
+		}
+	}
+
+	res := make([]string, 0, len(set))
+	for issue := range set {
+		res = append(res, issue)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func hstsMaxAge(hsts string) int {
+	for _, directive := range strings.Split(hsts, ";") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		maxAge, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(directive, "max-age="), `"`))
+		if err != nil {
+			return 0
+		}
+		return maxAge
+	}
+	return 0
+}
+
+// getHeadersReport groups pages by host, the most frequent issue set of host is its common issues
+func (p *CrawlerProcess) getHeadersReport() *headersReport {
+	if !p.crawlerService.conf.Security.Headers {
+		return nil
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	hostPages := make(map[string][]string)
+	for l := range p.headerIssues {
+		u, err := url.Parse(l)
+		if err != nil {
+			continue
+		}
+		hostPages[u.Host] = append(hostPages[u.Host], l)
+	}
+
+	res := &headersReport{
+		Hosts:      []hostHeaders{},
+		Deviations: []headerDeviation{},
+	}
+
+	for host, pages := range hostPages {
+		sort.Strings(pages)
+
+		summary := hostHeaders{Host: host, Pages: len(pages), IssueCounts: make(map[string]int)}
+		sets := make(map[string]int)
+		common := ""
+		for _, l := range pages {
+			issues := p.headerIssues[l]
+			for _, issue := range issues {
+				summary.IssueCounts[issue]++
+			}
+
+			key := strings.Join(issues, ",")
+			sets[key]++
+			if sets[key] > sets[common] || (sets[key] == sets[common] && key < common) {
+				common = key
+			}
+		}
+		summary.Issues = splitIssues(common)
+		res.Hosts = append(res.Hosts, summary)
+
+		for _, l := range pages {
+			issues := p.headerIssues[l]
+			if strings.Join(issues, ",") == common {
+				continue
+			}
+			res.Deviations = append(res.Deviations, headerDeviation{
+				Url:   l,
+				Extra: diffIssues(issues, summary.Issues),
+				Fixed: diffIssues(summary.Issues, issues),
+			})
+		}
+	}
+
+	sort.Slice(res.Hosts, func(i, j int) bool { return res.Hosts[i].Host < res.Hosts[j].Host })
+	sort.Slice(res.Deviations, func(i, j int) bool { return res.Deviations[i].Url < res.Deviations[j].Url })
+
+	return res
+}
+
+func splitIssues(key string) []string {
+	if key == "" {
+		return []string{}
+	}
+	return strings.Split(key, ",")
+}
+
+// diffIssues returns issues of a which are not in b
+func diffIssues(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, issue := range b {
+		in[issue] = true
+	}
+
+	res := make([]string, 0)
+	for _, issue := range a {
+		if !in[issue] {
+			res = append(res, issue)
+		}
+	}
+	return res
+}
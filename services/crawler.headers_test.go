@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeaderIssues(t *testing.T) {
+	header := http.Header{}
+	header.Set("Strict-Transport-Security", "max-age=3600; includeSubDomains")
+	header.Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'none'")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Referrer-Policy", "unsafe-url")
+	header.Add("Set-Cookie", "session=1; Secure; HttpOnly; SameSite=Lax")
+	header.Add("Set-Cookie", "theme=dark")
+
+	issues := securityHeaderIssues("https://example.com/", header)
+	expected := []string{
+		headerCookieNoHttpOnly + " theme",
+		headerCookieNoSameSite + " theme",
+		headerCookieNoSecure + " theme",
+		headerHstsShortMaxAge,
+		headerReferrerUnsafe,
+	}
+	if strings.Join(issues, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected issues %v, got %v", expected, issues)
+	}
+
+	issues = securityHeaderIssues("http://example.com/", http.Header{})
+	expected = []string{headerCspMissing, headerReferrerMissing, headerFrameOptionsMissing, headerNosniffMissing}
+	if len(issues) != len(expected) {
+		t.Errorf("expected issues %v, got %v", expected, issues)
+	}
+	for _, issue := range issues {
+		if issue == headerHstsMissing {
+			t.Errorf("hsts should not be required over http")
+		}
+	}
+}
+
+func TestHeadersReport(t *testing.T) {
+	conf := config.CrawlerConfig{}
+	conf.Security.Headers = true
+	p := &CrawlerProcess{
+		crawlerService: &CrawlerService{conf: conf},
+		headerIssues: map[string][]string{
+			"https://a.com/1": {"csp-missing", "hsts-missing"},
+			"https://a.com/2": {"csp-missing", "hsts-missing"},
+			"https://a.com/3": {"csp-missing", "referrer-policy-missing"},
+			"https://b.com/":  {},
+			// equally frequent sets of host, the first one in order is common
+			"https://c.com/1": {"hsts-missing"},
+			"https://c.com/2": {"csp-missing"},
+		},
+	}
+
+	res := p.getHeadersReport()
+	hosts := make([]string, 0, len(res.Hosts))
+	for _, h := range res.Hosts {
+		hosts = append(hosts, fmt.Sprintf("%s %d %v", h.Host, h.Pages, h.Issues))
+	}
+	expected := []string{"a.com 3 [csp-missing hsts-missing]", "b.com 1 []", "c.com 2 [csp-missing]"}
+	if strings.Join(hosts, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected hosts %v, got %v", expected, hosts)
+	}
+	if counts := res.Hosts[0].IssueCounts; counts["csp-missing"] != 3 || counts["hsts-missing"] != 2 || counts["referrer-policy-missing"] != 1 {
+		t.Errorf("unexpected issue counts of a.com %v", counts)
+	}
+
+	deviations := make([]string, 0, len(res.Deviations))
+	for _, d := range res.Deviations {
+		deviations = append(deviations, fmt.Sprintf("%s extra %v fixed %v", d.Url, d.Extra, d.Fixed))
+	}
+	expected = []string{
+		"https://a.com/3 extra [referrer-policy-missing] fixed [hsts-missing]",
+		"https://c.com/1 extra [hsts-missing] fixed [csp-missing]",
+	}
+	if strings.Join(deviations, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected deviations %v, got %v", expected, deviations)
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	pageRankDamping    = 0.85
+	pageRankIterations = 100
+	pageRankEpsilon    = 1e-9
+
+	defaultDeepDepth = 4
+)
+
+// PageMetrics are link metrics of page in ranked tables of analysis
+type PageMetrics struct {
+	Url        string  `json:"url"`
+	PageRank   float64 `json:"page_rank"`
+	InDegree   int     `json:"in_degree"`
+	OutDegree  int     `json:"out_degree"`
+	Depth      int     `json:"depth"`       // depth at which crawler found the page
+	ClickDepth int     `json:"click_depth"` // shortest path from the root by links, -1 if unreachable
+}
+
+type linkAnalysis struct {
+	PagesCount int           `json:"pages_count"`
+	TopPages   []PageMetrics `json:"top_pages"`  // by PageRank
+	Orphans    []PageMetrics `json:"orphans"`    // no inner links point to the page
+	DeadEnds   []PageMetrics `json:"dead_ends"`  // crawled pages without inner links
+	DeepPages  []PageMetrics `json:"deep_pages"` // reachable only through deep paths
+}
+
+// getLinkAnalysis computes internal PageRank over pages found by crawler,
+// nofollow links do not pass link equity
+func (p *CrawlerProcess) getLinkAnalysis() *linkAnalysis {
+	conf := p.crawlerService.conf.Analysis
+	if !conf.Enabled {
+		return nil
+	}
+
+	deepDepth := conf.DeepDepth
+	if deepDepth <= 0 {
+		deepDepth = defaultDeepDepth
+	}
+
+	p.mux.RLock()
+	index := make(map[string]int, len(p.sitemap))
+	metrics := make([]PageMetrics, 0, len(p.sitemap))
+	for l, depth := range p.sitemap {
+		index[l] = len(metrics)
+		metrics = append(metrics, PageMetrics{Url: l, Depth: depth, ClickDepth: -1})
+	}
+
+	crawled := make([]bool, len(metrics))
+	for i, m := range metrics {
+		_, crawled[i] = p.data[m.Url]
+	}
+
+	// unique links between inner pages, out is without nofollow links
+	all := make([][]int, len(metrics))
+	out := make([][]int, len(metrics))
+	seen := make(map[[2]int]bool)
+	// inner targets which were not queued (beyond depth limit, nofollow, traps)
+	// are not ranked but their links are out links of page
+	seenBeyond := make(map[crawlerEdge]bool)
+	for e := range p.edges {
+		s, ok := index[e.Source]
+		if !ok {
+			continue
+		}
+		t, ok := index[e.Target]
+		if !ok {
+			key := crawlerEdge{Source: e.Source, Target: e.Target}
+			if e.Source != e.Target && !seenBeyond[key] && p.inScope(e.Target) {
+				seenBeyond[key] = true
+				metrics[s].OutDegree++
+			}
+			continue
+		}
+		if s == t || seen[[2]int{s, t}] {
+			continue
+		}
+		seen[[2]int{s, t}] = true
+
+		metrics[s].OutDegree++
+		metrics[t].InDegree++
+		all[s] = append(all[s], t)
+		if !hasRel(e.Rel, "nofollow") {
+			out[s] = append(out[s], t)
+		}
+	}
+	root, ok := index[p.rootUrl]
+	if !ok {
+		root = -1
+	}
+	p.mux.RUnlock()
+
+	for i, r := range pageRank(out) {
+		metrics[i].PageRank = r
+	}
+
+	if root >= 0 {
+		for i, d := range clickDepths(all, root) {
+			metrics[i].ClickDepth = d
+		}
+	}
+
+	res := &linkAnalysis{
+		PagesCount: len(metrics),
+		TopPages:   []PageMetrics{},
+		Orphans:    []PageMetrics{},
+		DeadEnds:   []PageMetrics{},
+		DeepPages:  []PageMetrics{},
+	}
+	for i, m := range metrics {
+		res.TopPages = append(res.TopPages, m)
+		if m.InDegree == 0 && i != root {
+			res.Orphans = append(res.Orphans, m)
+		}
+		if m.OutDegree == 0 && crawled[i] {
+			res.DeadEnds = append(res.DeadEnds, m)
+		}
+		if m.ClickDepth >= deepDepth {
+			res.DeepPages = append(res.DeepPages, m)
+		}
+	}
+
+	res.TopPages = rankPages(res.TopPages, conf.Top)
+	res.Orphans = rankPages(res.Orphans, conf.Top)
+	res.DeadEnds = rankPages(res.DeadEnds, conf.Top)
+	res.DeepPages = rankPages(res.DeepPages, conf.Top)
+
+	return res
+}
+
+// pageRank computes ranks by power iteration, rank of dangling pages
+// is distributed over all pages
+func pageRank(out [][]int) []float64 {
+	n := len(out)
+	rank := make([]float64, n)
+	if n == 0 {
+		return rank
+	}
+
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+
+	next := make([]float64, n)
+	for it := 0; it < pageRankIterations; it++ {
+		dangling := 0.0
+		for i := range out {
+			if len(out[i]) == 0 {
+				dangling += rank[i]
+			}
+		}
+
+		base := (1-pageRankDamping)/float64(n) + pageRankDamping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for i, targets := range out {
+			if len(targets) == 0 {
+				continue
+			}
+			share := pageRankDamping * rank[i] / float64(len(targets))
+			for _, t := range targets {
+				next[t] += share
+			}
+		}
+
+		diff := 0.0
+		for i := range rank {
+			diff += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if diff < pageRankEpsilon {
+			break
+		}
+	}
+
+	return rank
+}
+
+// clickDepths finds shortest paths from the root by BFS
+func clickDepths(out [][]int, root int) []int {
+	depths := make([]int, len(out))
+	for i := range depths {
+		depths[i] = -1
+	}
+	depths[root] = 0
+
+	queue := []int{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, t := range out[cur] {
+			if depths[t] == -1 {
+				depths[t] = depths[cur] + 1
+				queue = append(queue, t)
+			}
+		}
+	}
+
+	return depths
+}
+
+// rankPages sorts pages by PageRank desc and cuts the list to top
+func rankPages(pages []PageMetrics, top int) []PageMetrics {
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].PageRank != pages[j].PageRank {
+			return pages[i].PageRank > pages[j].PageRank
+		}
+		return pages[i].Url < pages[j].Url
+	})
+	if top > 0 && len(pages) > top {
+		pages = pages[:top]
+	}
+	return pages
+}
+
+func hasRel(rel, value string) bool {
+	for _, r := range strings.Fields(rel) {
+		if r == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"go-link-crawler/config"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPageRank(t *testing.T) {
+	// 0 -> 1, 2 -> 1, 1 -> 0, 3 is dangling
+	out := [][]int{{1}, {0}, {1}, {}}
+	rank := pageRank(out)
+
+	sum := 0.0
+	for _, r := range rank {
+		sum += r
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		t.Errorf("ranks sum should be 1, got %f", sum)
+	}
+
+	for i := range rank {
+		if i != 1 && rank[i] >= rank[1] {
+			t.Errorf("page 1 should have the highest rank, got %v", rank)
+		}
+	}
+}
+
+func TestClickDepths(t *testing.T) {
+	out := [][]int{{1, 2}, {3}, {3}, {}, {0}}
+	depths := clickDepths(out, 0)
+
+	expected := []int{0, 1, 1, 2, -1}
+	for i := range expected {
+		if depths[i] != expected[i] {
+			t.Errorf("page %d depth expected %d, got %d", i, expected[i], depths[i])
+		}
+	}
+}
+
+func TestLinkAnalysisDepthLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/a">a</a><a href="/end">end</a>`)
+		case "/a":
+			fmt.Fprint(w, `<a href="/b">b</a><a href="https://ext.com/">ext</a>`)
+		default:
+			fmt.Fprint(w, `<a href="https://ext.com/">ext</a>`)
+		}
+	}))
+	defer srv.Close()
+
+	conf := config.CrawlerConfig{Depth: 2, Workers: 2}
+	conf.Analysis.Enabled = true
+	s := newTestCrawlerService(srv, conf)
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+
+	if res.Analysis.PagesCount != 3 {
+		t.Errorf("expected 3 ranked pages, got %d", res.Analysis.PagesCount)
+	}
+	// /a links to /b beyond depth limit, it is not a dead end
+	if len(res.Analysis.DeadEnds) != 1 || res.Analysis.DeadEnds[0].Url != srv.URL+"/end" {
+		t.Errorf("expected only /end dead end, got %+v", res.Analysis.DeadEnds)
+	}
+}
@@ -1,90 +1,308 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"github.com/PuerkitoBio/goquery"
 	"go-link-crawler/log"
 	"go-link-crawler/utils"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
 type CrawlerProcess struct {
-	crawlerService *CrawlerService
-	createdAt      time.Time
-	uri            *url.URL
-	Error          error
-	Completed      bool
-	sitemap        map[string]int // url -> depth
-	data           map[string]crawlerLinkData
-	external       map[string]bool
-	links          chan crawlerLink
-	workers        int32
-	linksCount     int32
-	wg             sync.WaitGroup
-	mux            sync.RWMutex
-	ctx            context.Context
-	cancel         context.CancelFunc
+	crawlerService        *CrawlerService
+	createdAt             time.Time
+	uri                   *url.URL
+	rootUrl               string
+	seed                  Seed
+	Error                 error
+	Completed             bool
+	stopped               bool
+	startedAt             time.Time
+	finishedAt            time.Time
+	sitemap               map[string]int // url -> depth, it is empty with bloom visited set
+	visited               *utils.ScalableBloomFilter
+	data                  map[string]crawlerLinkData
+	pages                 *pageStore
+	robots                map[string]crawlerLinkData // status and robots of spilled pages which are not indexable or followed
+	external              map[string]bool
+	externalVisited       *utils.ScalableBloomFilter
+	externalCount         int
+	firstStart            time.Time
+	lastEnd               time.Time
+	requests              int
+	errorPages            int             // pages with error status
+	nofollow              map[string]bool // targets of rel=nofollow links
+	edges                 map[crawlerEdge]bool
+	redirects             []crawlerRedirect
+	duplicates            *duplicateIndex
+	traps                 *trapDetector
+	referrers             map[string][]string
+	fragments             map[string]map[string][]string // page -> fragment -> referrers
+	failures              map[string]string
+	securityFindings      []crawlerFinding
+	httpsProbed           map[string]bool // hosts whose http version is probed
+	httpsProbes           []string        // https pages whose http version waits for probe
+	headerIssues          map[string][]string
+	accessibilityFindings []crawlerFinding
+	skippedDuplicates     []string
+	listed                map[string]bool // urls listed in sitemap.xml
+	linked                map[string]bool // urls found in <a> tags
+	sitemapFiles          []string
+	frontier              *frontier
+	limiter               *concurrencyLimiter
+	events                *eventBus
+	done                  chan struct{}
+	running               int // count of running workers
+	wg                    sync.WaitGroup
+	mux                   sync.RWMutex
+	interrupted           []crawlerLink // links which were not crawled because of stop
+	dropped               []string      // links which did not fit in frontier
+	resumed               []crawlerLink // queue of resumed checkpoint
+	dequeue               func() bool   // removes process waiting for free site slot from scheduler
+	ctx                   context.Context
+	cancel                context.CancelFunc
+	requestCtx            context.Context // requests in flight are aborted by it after grace period of stop
+	abort                 context.CancelFunc
 }
 
 type crawlerLink struct {
 	Url   string
 	Depth int
+	// Referrer is page where link was found first
+	Referrer string
+}
+
+// crawlerHref is <a> tag found on page
+type crawlerHref struct {
+	Url  string
+	Text string
+	Rel  string
+}
+
+// crawlerPage is parsed page content
+type crawlerPage struct {
+	Title      string
+	Links      []crawlerHref
+	Meta       *pageMeta
+	Robots     []string // meta robots directives
+	Refresh    string   // meta refresh target
+	Canonicals []string
+	Text       string // visible text
+	Anchors    map[string]bool
+	Resources  []pageResource
+	// Accessibility findings without url
+	Accessibility []crawlerFinding
+	// Extracted values of extraction rules
+	Extracted map[string]interface{}
+}
+
+// crawlerResponse is fetched page
+type crawlerResponse struct {
+	Url        string // url after redirects
+	StatusCode int
+	Header     http.Header
+	Body       []byte
 }
 
 type crawlerLinkData struct {
-	Title string
-	Start time.Time
-	Since time.Duration
+	Title       string
+	StatusCode  int
+	Meta        *pageMeta
+	Robots      *pageRobots
+	Canonicals  []string
+	ContentHash string
+	SimHash     uint64
+	Anchors     map[string]bool
+	Extracted   map[string]interface{}
+	Start       time.Time
+	Since       time.Duration
 }
 
-func (s *CrawlerService) newCrawlerProcess(rawUrl string) (*CrawlerProcess, error) {
+// newCrawlerProcess creates process of seed, pagesFile is pages file of resumed crawl
+func (s *CrawlerService) newCrawlerProcess(seed Seed, pagesFile string) (*CrawlerProcess, error) {
+	if err := checkDuplicatesConfig(s.conf.Duplicates); err != nil {
+		log.WithTrace("CrawlerService", "newCrawlerProcess").Errorf("checkDuplicatesConfig err: %v", err)
+		return nil, err
+	}
+	if err := checkAccessibilityConfig(s.conf.Accessibility); err != nil {
+		log.WithTrace("CrawlerService", "newCrawlerProcess").Errorf("checkAccessibilityConfig err: %v", err)
+		return nil, err
+	}
+
+	rawUrl := seed.Url
 	uri, err := url.Parse(rawUrl)
 	if err != nil {
 		log.WithTrace("CrawlerService", "newCrawlerProcess").Errorf("url.Parse err: %v", err)
 		return nil, err
 	}
 
-	uri.Host = strings.TrimLeft(uri.Host, "www.")
+	uri.Host = strings.TrimPrefix(uri.Host, "www.")
+
+	s.mux.RLock()
+	score := s.frontierScore
+	s.mux.RUnlock()
+	if score == nil {
+		score = DefaultFrontierScore
+	}
+	score = boostFrontierScore(score, s.frontierBoosts)
 
-	return &CrawlerProcess{
+	// process is stopped by own context, all processes by context of service
+	ctx, cancel := context.WithCancel(s.ctx)
+	requestCtx, abort := context.WithCancel(s.ctx)
+
+	p := &CrawlerProcess{
 		crawlerService: s,
 		createdAt:      time.Now(),
 		uri:            uri,
+		rootUrl:        rawUrl,
+		seed:           seed,
 		sitemap:        make(map[string]int),
 		data:           make(map[string]crawlerLinkData),
+		robots:         make(map[string]crawlerLinkData),
 		external:       make(map[string]bool),
-		links:          make(chan crawlerLink),
-		workers:        0,
-		linksCount:     0,
+		nofollow:       make(map[string]bool),
+		edges:          make(map[crawlerEdge]bool),
+		duplicates:     newDuplicateIndex(),
+		traps:          newTrapDetector(),
+		referrers:      make(map[string][]string),
+		fragments:      make(map[string]map[string][]string),
+		failures:       make(map[string]string),
+		headerIssues:   make(map[string][]string),
+		httpsProbed:    make(map[string]bool),
+		listed:         make(map[string]bool),
+		linked:         make(map[string]bool),
+		frontier:       newFrontier(s.conf.Frontier.Strategy, s.conf.Frontier.MaxSize, score),
+		limiter:        newConcurrencyLimiter(s.conf.Workers, s.conf.Adaptive),
+		events:         newEventBus(),
+		done:           make(chan struct{}),
 		mux:            sync.RWMutex{},
-		ctx:            s.ctx,
-		cancel:         s.cancel,
-	}, nil
+		ctx:            ctx,
+		cancel:         cancel,
+		requestCtx:     requestCtx,
+		abort:          abort,
+	}
+	if err := p.initStorage(pagesFile); err != nil {
+		cancel()
+		abort()
+		log.WithTrace("CrawlerService", "newCrawlerProcess").Errorf("p.initStorage err: %v", err)
+		return nil, err
+	}
+
+	return p, nil
 }
 
+// schedule starts process when scheduler has free site slot
+func (p *CrawlerProcess) schedule() {
+	dequeue := p.crawlerService.scheduler.startSite(p.run)
+
+	p.mux.Lock()
+	p.dequeue = dequeue
+	p.mux.Unlock()
+}
+
+// seedLinks marks and returns the first links of crawl, it has to be called under p.mux lock
+func (p *CrawlerProcess) seedLinks() []crawlerLink {
+	if len(p.resumed) > 0 {
+		return p.resumed
+	}
+	p.markVisited(p.rootUrl, 0)
+	return []crawlerLink{{Url: p.rootUrl, Depth: 0}}
+}
+
+// run puts the first link and starts workers, done is closed when all workers are finished
+func (p *CrawlerProcess) run() {
+	log.WithTrace("CrawlerService", "Start").Trace("crawl link: ", p.rootUrl)
+	p.mux.Lock()
+	p.startedAt = time.Now()
+	links := p.seedLinks()
+	// process stopped while it was queued is started by scheduler before it is dequeued
+	stopped := p.stopped
+	if stopped {
+		p.interrupted = append(p.interrupted, links...)
+	}
+	p.mux.Unlock()
+	if !stopped {
+		for _, link := range links {
+			p.frontier.push(link, false)
+		}
+	}
+
+	// worker pools
+	p.mux.Lock()
+	for i := 0; i < p.limiter.workers(); i++ {
+		p.runWorker()
+	}
+	p.mux.Unlock()
+
+	go func() {
+		p.wg.Wait()
+		p.crawlerService.scheduler.finishSite()
+		p.finish()
+	}()
+
+	go func() {
+		select {
+		case <-p.ctx.Done():
+			log.WithTrace("CrawlerService", "Start", "worker").Debug("workers canceled by context")
+			p.frontier.close()
+		case <-p.done:
+		}
+	}()
+}
+
+// finish completes process, its storage is closed and done is closed
+func (p *CrawlerProcess) finish() {
+	// contexts of finished process are released
+	p.cancel()
+	p.abort()
+	p.mux.Lock()
+	p.Completed = true
+	p.finishedAt = time.Now()
+	p.mux.Unlock()
+	p.closeStorage()
+	close(p.done)
+	p.emitDone()
+}
+
+// runWorker starts worker taking links from frontier, it has to be called under p.mux lock
 func (p *CrawlerProcess) runWorker() {
+	p.running++
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
+		defer func() {
+			p.mux.Lock()
+			p.running--
+			p.mux.Unlock()
+		}()
 		for {
-			select {
-			case <-p.ctx.Done():
-				log.WithTrace("CrawlerService", "Start", "worker").Debug("worker canceled by context")
+			// link is taken only when request can be sent, so links wait in frontier and keep their order
+			if err := p.limiter.acquire(p.ctx); err != nil {
 				return
-			case link, more := <-p.links:
-				if more {
-					if err := p.processLink(link); err != nil {
-						continue
-					}
-				} else {
-					return
-				}
 			}
+			link, ok := p.frontier.pop()
+			if !ok {
+				p.limiter.release()
+				return
+			}
+			// request slot is taken only for link processing, fairly across hosts
+			if err := p.crawlerService.scheduler.acquire(p.ctx, p.uri.Host); err != nil {
+				p.addInterrupted(link)
+				p.limiter.release()
+				p.frontier.done()
+				return
+			}
+			p.processLink(link)
+			p.crawlerService.scheduler.release()
+			p.limiter.release()
+			p.runHttpsProbes()
+			p.frontier.done()
 		}
 	}()
 }
@@ -93,128 +311,328 @@ func (p *CrawlerProcess) processLink(link crawlerLink) error {
 	log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("start processing link: %s", link.Url)
 
 	start := time.Now()
-	atomic.AddInt32(&p.workers, 1)
-	defer atomic.AddInt32(&p.workers, -1)
-	atomic.AddInt32(&p.linksCount, -1)
 
 	// request body
-	body, err := p.requestBody(link)
+	res, err := p.requestBody(link)
+	if err != nil && p.requestCtx.Err() != nil {
+		// request was aborted by stop of crawl
+		p.addInterrupted(link)
+		return err
+	}
+	p.limiter.observe(p.uri.Host, start, time.Since(start), res.StatusCode)
 	if err != nil {
+		p.addFailure(link, err)
+		p.emitError(link, err)
 		return err
 	}
 
 	// get title & links
-	title, links, err := p.parseData(body)
+	page, err := p.parseData(res.Body)
 	if err != nil {
 		log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Errorf("p.parseData(body) link: %s err: %v", link.Url, err)
+		p.emitError(link, err)
 		return err
 	}
 
-	log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("title: %s link: %s", title, link.Url)
+	log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("title: %s link: %s", page.Title, link.Url)
 
-	// process new links
-	p.processNewLinks(link, links)
+	// seed frontier from sitemaps on the root link
+	if link.Depth == 0 && p.crawlerService.conf.Sitemap.Enabled {
+		p.processSitemapLinks(link)
+	}
 
-	// store data
-	p.mux.Lock()
-	p.data[link.Url] = crawlerLinkData{
-		Title: title,
-		Start: start,
-		Since: time.Since(start),
+	if page.Meta != nil {
+		page.Meta.RobotsHeader = strings.Join(res.Header["X-Robots-Tag"], ", ")
+	}
+
+	var robots *pageRobots
+	if p.robotsPolicy() != robotsPolicyIgnore {
+		robots = getPageRobots(res.StatusCode, res.Header, page.Robots)
+	}
+
+	if res.Url != link.Url {
+		p.addRedirect(link.Url, res.Url, redirectHttp)
+	}
+
+	p.checkSecurityHeaders(res)
+
+	if p.crawlerService.conf.Accessibility.Enabled {
+		p.addAccessibilityFindings(res.Url, page.Accessibility)
+	}
+
+	if p.crawlerService.conf.Security.MixedContent {
+		p.checkMixedContent(res.Url, page.Resources)
+		p.checkHttpsRedirect(link.Url, res.Url)
+		p.queueHttpsProbe(res.Url)
+	}
+
+	// meta refresh is a redirect
+	if page.Refresh != "" {
+		p.processRefresh(link, res.Url, page.Refresh)
+	}
+
+	skipLinks := false
+	canonicals := p.resolveUrls(page.Canonicals, res.Url)
+	if p.isCanonicalDuplicate(link, canonicals) {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("canonical %s is already known, skip links of %s", canonicals[0], link.Url)
+		skipLinks = true
+	}
+
+	var fp pageFingerprint
+	if p.crawlerService.conf.Duplicates.Enabled && page.Text != "" {
+		fp = getPageFingerprint(link.Url, page.Text)
+		if original, ok := p.isContentDuplicate(fp); ok {
+			log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("content of %s duplicates %s", link.Url, original)
+			skipLinks = skipLinks || p.crawlerService.conf.Duplicates.SkipLinks
+		}
+	}
+
+	// process new links, links of duplicates are skipped
+	if skipLinks {
+		p.processNewLinks(link, nil, robots)
+	} else {
+		p.processNewLinks(link, page.Links, robots)
 	}
-	p.mux.Unlock()
+
+	d := crawlerLinkData{
+		Title:       page.Title,
+		StatusCode:  res.StatusCode,
+		Meta:        page.Meta,
+		Robots:      robots,
+		Canonicals:  canonicals,
+		ContentHash: fp.Hash,
+		SimHash:     fp.SimHash,
+		Anchors:     page.Anchors,
+		Extracted:   page.Extracted,
+		Start:       start,
+		Since:       time.Since(start),
+	}
+	p.storePage(link, d)
+	p.emitPage(link, d)
 
 	return nil
 }
 
-func (p *CrawlerProcess) requestBody(link crawlerLink) ([]byte, error) {
-	res, err := p.crawlerService.httpClient.Get(link.Url)
+func (p *CrawlerProcess) requestBody(link crawlerLink) (crawlerResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, link.Url, nil)
+	if err != nil {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "requestBody").Errorf("http.NewRequest link: %s err: %v", link.Url, err)
+		return crawlerResponse{}, err
+	}
+
+	res, err := p.crawlerService.httpClient.Do(req.WithContext(p.requestCtx))
 	if err != nil {
 		log.WithTrace("CrawlerService", "CrawlerProcess", "requestBody").Errorf("p.crawlerService.httpClient.Get link: %s err: %v", link.Url, err)
-		return nil, err
+		return crawlerResponse{}, err
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		log.WithTrace("CrawlerService", "CrawlerProcess", "requestBody").Errorf("ioutil.ReadAll link: %s err: %v", link.Url, err)
-		return nil, err
+		return crawlerResponse{}, err
 	}
 	res.Body.Close()
 
-	return body, nil
+	return crawlerResponse{
+		Url:        res.Request.URL.String(),
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}, nil
 }
 
-func (p *CrawlerProcess) processNewLinks(link crawlerLink, links []string) {
+func (p *CrawlerProcess) processNewLinks(link crawlerLink, links []crawlerHref, robots *pageRobots) {
+	policy := p.robotsPolicy()
+	follow := policy != robotsPolicyRespect || robots == nil || robots.Follow
+	if !follow {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("links are not followed by robots directives on link request: %s", link.Url)
+	}
+
 	innerLinksCount := 0
 	for _, l := range links {
 		// check that it is correct url scheme
-		scheme := utils.GetUrlScheme(l)
+		scheme := utils.GetUrlScheme(l.Url)
 		if scheme != "http" && scheme != "https" && scheme != "" {
 			continue
 		}
 
-		fullUrl := utils.RelativeUrlToFull(l, link.Url, p.uri)
+		// fragments point to the same page
+		fullUrl, fragment := utils.SplitFragment(utils.RelativeUrlToFull(l.Url, link.Url, p.uri))
+		if fullUrl == "" {
+			continue
+		}
+		p.addEdge(link.Url, fullUrl, l)
 
-		if utils.IsInnerUrl(fullUrl, p.uri) {
-			depth := link.Depth + 1
-			if depth < p.crawlerService.conf.Depth {
+		if p.inScope(fullUrl) {
+			// referrers of bounded crawl are kept only for broken pages
+			if !p.bounded() {
 				p.mux.Lock()
-				if _, ok := p.sitemap[fullUrl]; !ok { // unique inner url
-					p.sitemap[fullUrl] = depth
-					p.mux.Unlock()
+				p.addReferrer(fullUrl, link.Url)
+				p.mux.Unlock()
+			}
 
-					log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("new inner link found: %s on link request: %s", fullUrl, link.Url)
-
-					innerLinksCount++
-					atomic.AddInt32(&p.linksCount, 1)
-					go func() { // prevent deadlock waiting
-						p.links <- crawlerLink{
-							Url:   fullUrl,
-							Depth: link.Depth + 1,
-						}
-					}()
-				} else {
+			p.addFragment(fullUrl, fragment, link.Url)
+			p.checkInsecureLink(link.Url, fullUrl)
+
+			if p.crawlerService.conf.Sitemap.Enabled {
+				p.mux.Lock()
+				p.linked[fullUrl] = true
+				p.mux.Unlock()
+			}
+
+			if policy != robotsPolicyIgnore && hasRel(l.Rel, "nofollow") {
+				// bounded crawl has no robots report
+				if !p.bounded() {
+					p.mux.Lock()
+					p.nofollow[fullUrl] = true
 					p.mux.Unlock()
 				}
+
+				if policy == robotsPolicyRespect {
+					continue
+				}
+			}
+
+			if follow && p.pushLink(fullUrl, link.Url, link.Depth+1) {
+				log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("new inner link found: %s on link request: %s", fullUrl, link.Url)
+				innerLinksCount++
 			}
 		} else {
 			log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("new external link found: %s on link request: %s", fullUrl, link.Url)
-			p.mux.Lock()
-			p.external[fullUrl] = true
-			p.mux.Unlock()
+			// referrers of external links are needed only for report of dead ones
+			if !p.bounded() && p.crawlerService.externalChecker != nil {
+				p.mux.Lock()
+				p.addReferrer(fullUrl, link.Url)
+				p.mux.Unlock()
+			}
+			if p.addExternal(fullUrl) {
+				p.checkExternalLink(fullUrl)
+			}
 		}
 	}
 
-	// there is no links and this is the last worker
 	if innerLinksCount == 0 {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("there is no new links on %s", link.Url)
+	}
+}
+
+// pushLink queues unique inner url that fits in depth limit
+func (p *CrawlerProcess) pushLink(fullUrl, referrer string, depth int) bool {
+	if depth >= p.maxDepth() {
+		return false
+	}
+
+	p.mux.Lock()
+	if p.isVisited(fullUrl) || p.isTrap(fullUrl) {
+		p.mux.Unlock()
+		return false
+	}
+	p.markVisited(fullUrl, depth)
+	listed := p.listed[fullUrl]
+	p.mux.Unlock()
+
+	if !p.queueLink(crawlerLink{Url: fullUrl, Depth: depth, Referrer: referrer}, listed) {
 		p.mux.Lock()
-		if p.workers == 1 && p.linksCount == 0 {
-			log.WithTrace("CrawlerService", "CrawlerProcess", "processLink").Tracef("There is no more links on %s => finish", p.uri.String())
-			close(p.links)
-		}
+		p.uncountTrap(fullUrl)
 		p.mux.Unlock()
+		return false
 	}
+
+	return true
 }
 
-func (p *CrawlerProcess) parseData(body []byte) (string, []string, error) {
-	var title string
-	var links []string
+// queueLink pushes link which is already marked as visited to frontier,
+// link found after stop is kept as interrupted to be crawled after resume
+func (p *CrawlerProcess) queueLink(link crawlerLink, listed bool) bool {
+	err := p.frontier.push(link, listed)
+	if err == nil {
+		return true
+	}
+	if err == errFrontierClosed {
+		p.addInterrupted(link)
+		return true
+	}
 
-	if p.crawlerService.conf.UseRegexForParsing {
-		title = p.parseReTitle(body)
-		links = p.parseReLinks(body)
-	} else {
-		gqBody, err := goquery.NewDocumentFromReader(
-			strings.NewReader(strings.ToLower(string(body))))
+	// dropped link can be queued again when it is found later
+	p.mux.Lock()
+	p.unmarkVisited(link.Url)
+	if err == errFrontierFull {
+		p.dropped = append(p.dropped, link.Url)
+	}
+	p.mux.Unlock()
+	return false
+}
+
+func (p *CrawlerProcess) parseData(body []byte) (crawlerPage, error) {
+	page := crawlerPage{}
+
+	// page checks work with document even if regex parsing is used
+	var gqBody *goquery.Document
+	if !p.crawlerService.conf.UseRegexForParsing || p.needsDocument() {
+		// html parser lowercases tag and attribute names itself,
+		// values (urls, anchor texts) are kept as is
+		var err error
+		gqBody, err = goquery.NewDocumentFromReader(bytes.NewReader(body))
 		if err != nil {
-			return "", nil, err
+			return page, err
 		}
+	}
 
-		title = p.parseGoqueryTitle(gqBody)
-		links = p.parseGoqueryLinks(gqBody)
+	if p.crawlerService.conf.UseRegexForParsing {
+		page.Title = p.parseReTitle(body)
+		// regex does not capture rel and text of links, so they are taken from document if it is parsed anyway
+		if gqBody != nil {
+			page.Links = p.parseGoqueryLinks(gqBody)
+		} else {
+			page.Links = p.parseReLinks(body)
+		}
+		page.Refresh, page.Canonicals = p.parseReHeadTags(body)
+	} else {
+		page.Title = p.parseGoqueryTitle(gqBody)
+		page.Links = p.parseGoqueryLinks(gqBody)
+		page.Refresh, page.Canonicals = p.parseGoqueryHeadTags(gqBody)
+	}
+
+	if p.crawlerService.conf.Seo.Enabled {
+		page.Meta = p.parseGoqueryMeta(gqBody)
+	}
+
+	if p.robotsPolicy() != robotsPolicyIgnore {
+		page.Robots = p.parseGoqueryRobots(gqBody)
+	}
+
+	if p.crawlerService.conf.Duplicates.Enabled {
+		page.Text = p.parseGoqueryText(gqBody)
+	}
+
+	if p.crawlerService.conf.CheckFragments {
+		page.Anchors = p.parseGoqueryAnchors(gqBody)
+	}
+
+	if p.crawlerService.conf.Security.MixedContent {
+		page.Resources = p.parseGoqueryResources(gqBody)
+	}
+
+	if p.crawlerService.conf.Accessibility.Enabled {
+		page.Accessibility = p.parseGoqueryAccessibility(gqBody)
 	}
 
-	return title, links, nil
+	if len(p.crawlerService.extractors) > 0 {
+		page.Extracted = p.parseGoqueryExtract(gqBody)
+	}
+
+	return page, nil
+}
+
+// needsDocument reports that goquery document is required by page checks
+func (p *CrawlerProcess) needsDocument() bool {
+	return p.crawlerService.conf.Seo.Enabled ||
+		p.robotsPolicy() != robotsPolicyIgnore ||
+		p.crawlerService.conf.Duplicates.Enabled ||
+		p.crawlerService.conf.CheckFragments ||
+		p.crawlerService.conf.Security.MixedContent ||
+		p.crawlerService.conf.Accessibility.Enabled ||
+		len(p.crawlerService.extractors) > 0
 }
 
 func (p *CrawlerProcess) parseReTitle(body []byte) string {
@@ -225,11 +643,11 @@ func (p *CrawlerProcess) parseReTitle(body []byte) string {
 	return ""
 }
 
-func (p *CrawlerProcess) parseReLinks(body []byte) []string {
+func (p *CrawlerProcess) parseReLinks(body []byte) []crawlerHref {
 	matches := reLink.FindAllSubmatch(body, -1)
-	res := make([]string, 0)
+	res := make([]crawlerHref, 0)
 	for _, m := range matches {
-		res = append(res, string(m[1]))
+		res = append(res, crawlerHref{Url: string(m[1])})
 	}
 	return res
 }
@@ -242,11 +660,16 @@ func (p *CrawlerProcess) parseGoqueryTitle(body *goquery.Document) string {
 	return title
 }
 
-func (p *CrawlerProcess) parseGoqueryLinks(body *goquery.Document) []string {
-	res := make([]string, 0)
+func (p *CrawlerProcess) parseGoqueryLinks(body *goquery.Document) []crawlerHref {
+	res := make([]crawlerHref, 0)
 	body.Find("a").Each(func(i int, s *goquery.Selection) {
 		if link, ok := s.Attr("href"); ok && link != "" {
-			res = append(res, link)
+			rel, _ := s.Attr("rel")
+			res = append(res, crawlerHref{
+				Url:  link,
+				Text: strings.Join(strings.Fields(s.Text()), " "),
+				Rel:  strings.ToLower(strings.TrimSpace(rel)),
+			})
 		}
 	})
 	return res
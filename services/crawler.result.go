@@ -1,56 +1,118 @@
 package services
 
-import "time"
-
-type crawlerResult struct {
-	Domain             string
-	Sitemap            map[string]string `json:"sitemap"`
-	InnerLinksCount    int               `json:"inner_links_count"`
-	ExternalLinks      []string          `json:"external_links"`
-	ExternalLinksCount int               `json:"external_links_count"`
-	RequestsPerSec     float32           `json:"requests_per_sec"`
+// CrawlerResult is summary of crawled domain
+type CrawlerResult struct {
+	Domain                string
+	Tags                  []string              `json:"tags,omitempty"`
+	Interrupted           bool                  `json:"interrupted,omitempty"` // crawl was stopped, result is partial
+	Sitemap               map[string]string     `json:"sitemap"`
+	InnerLinksCount       int                   `json:"inner_links_count"`
+	ExternalLinks         []string              `json:"external_links"`
+	ExternalLinksCount    int                   `json:"external_links_count"`
+	RequestsPerSec        float32               `json:"requests_per_sec"`
+	SitemapXml            *sitemapReport        `json:"sitemap_xml,omitempty"`
+	Graph                 *linkGraph            `json:"graph,omitempty"`
+	Analysis              *linkAnalysis         `json:"analysis,omitempty"`
+	Pages                 map[string]pageResult `json:"pages"`
+	SeoFindings           []crawlerFinding      `json:"seo_findings,omitempty"`
+	Robots                *robotsReport         `json:"robots,omitempty"`
+	Redirects             []crawlerRedirect     `json:"redirects,omitempty"`
+	Canonicals            *canonicalReport      `json:"canonicals,omitempty"`
+	Duplicates            *duplicateReport      `json:"duplicates,omitempty"`
+	Traps                 []crawlerTrap         `json:"traps,omitempty"`
+	BrokenLinks           []brokenLink          `json:"broken_links"`
+	DeadExternalLinks     []brokenLink          `json:"dead_external_links,omitempty"`
+	Security              *securityReport       `json:"security,omitempty"`
+	SecurityHeaders       *headersReport        `json:"security_headers,omitempty"`
+	AccessibilityFindings []crawlerFinding      `json:"accessibility_findings,omitempty"`
+	Frontier              frontierStats         `json:"frontier"`
+	Concurrency           *concurrencyReport    `json:"concurrency,omitempty"`
+	// PagesFile has pages instead of Pages and Sitemap if pages are spilled to file
+	PagesFile string `json:"pages_file,omitempty"`
+}
+
+type pageResult struct {
+	Title       string      `json:"title"`
+	StatusCode  int         `json:"status_code"`
+	Meta        *pageMeta   `json:"meta,omitempty"`
+	Robots      *pageRobots `json:"robots,omitempty"`
+	ContentHash string      `json:"content_hash,omitempty"`
+	SimHash     string      `json:"simhash,omitempty"`
+	// Extracted values are string or []string for multi value rules
+	Extracted map[string]interface{} `json:"extracted,omitempty"`
 }
 
 func (p *CrawlerProcess) RequestsPerSec() float32 {
-	start := time.Now()
-	end := time.Now().AddDate(-1, 0, 0)
-	requests := 0
-	for _, d := range p.data {
-		if d.Start.Before(start) {
-			start = d.Start
-		}
-		s := d.Start.Add(d.Since)
-		if s.After(end) {
-			end = s
-		}
-		requests++
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	d := p.lastEnd.Sub(p.firstStart)
+	if p.requests == 0 || d <= 0 {
+		return 0
 	}
+	return float32(p.requests) * 1000000000 / float32(d.Nanoseconds())
+}
 
-	d := end.Sub(start)
-	return float32(requests) * 1000000000 / float32(d.Nanoseconds())
+func newPageResult(d crawlerLinkData) pageResult {
+	page := pageResult{
+		Title:      d.Title,
+		StatusCode: d.StatusCode,
+		Meta:       d.Meta,
+		Robots:     d.Robots,
+		Extracted:  d.Extracted,
+	}
+	if d.ContentHash != "" {
+		page.ContentHash = d.ContentHash
+		page.SimHash = formatSimHash(d.SimHash)
+	}
+	return page
 }
 
-func (p *CrawlerProcess) GetResult() crawlerResult {
-	p.wg.Wait()
+func (p *CrawlerProcess) GetResult() CrawlerResult {
+	<-p.done
 
-	res := crawlerResult{
+	res := CrawlerResult{
 		Domain:         p.uri.Host,
+		Tags:           p.seed.Tags,
 		Sitemap:        map[string]string{},
+		Pages:          map[string]pageResult{},
 		ExternalLinks:  []string{},
 		RequestsPerSec: 0,
+		Interrupted:    p.Interrupted(),
 	}
 
 	for l, d := range p.data {
 		res.Sitemap[l] = d.Title
-		res.InnerLinksCount++
+		res.Pages[l] = newPageResult(d)
+	}
+	if p.pages != nil {
+		res.PagesFile = p.pages.path
 	}
+	res.InnerLinksCount = p.requests
 
 	for l, _ := range p.external {
 		res.ExternalLinks = append(res.ExternalLinks, l)
-		res.ExternalLinksCount++
 	}
+	res.ExternalLinksCount = p.externalCount
 
 	res.RequestsPerSec = p.RequestsPerSec()
+	res.SitemapXml = p.getSitemapReport()
+	res.Graph = p.getGraph()
+	res.Analysis = p.getLinkAnalysis()
+	res.SeoFindings = p.getSeoFindings()
+	res.Robots = p.getRobotsReport()
+	res.Redirects = p.getRedirects()
+	res.Canonicals = p.getCanonicalReport()
+	res.Duplicates = p.getDuplicateReport()
+	res.Traps = p.getTraps()
+	res.BrokenLinks = p.getBrokenLinks()
+	res.DeadExternalLinks = p.getDeadExternalLinks()
+	res.Security = p.getSecurityReport()
+	res.SecurityHeaders = p.getHeadersReport()
+	res.AccessibilityFindings = p.getAccessibilityFindings()
+	res.Frontier = p.frontier.getStats()
+	res.Frontier.DroppedUrls = p.getDroppedUrls()
+	res.Concurrency = p.limiter.getReport()
 
 	return res
 }
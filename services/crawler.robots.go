@@ -0,0 +1,149 @@
+package services
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	robotsPolicyIgnore  = "ignore"
+	robotsPolicyRespect = "respect"
+	robotsPolicyReport  = "report"
+)
+
+// pageRobots is indexability of page by meta robots and X-Robots-Tag
+type pageRobots struct {
+	Directives []string `json:"directives"`
+	Indexable  bool     `json:"indexable"`
+	Follow     bool     `json:"follow"`
+}
+
+type robotsReport struct {
+	Policy        string   `json:"policy"`
+	NoindexPages  []string `json:"noindex_pages"`
+	NofollowPages []string `json:"nofollow_pages"`
+	NofollowLinks []string `json:"nofollow_links"` // targets of rel=nofollow links
+}
+
+func (p *CrawlerProcess) robotsPolicy() string {
+	switch p.crawlerService.conf.RobotsPolicy {
+	case robotsPolicyRespect, robotsPolicyReport:
+		return p.crawlerService.conf.RobotsPolicy
+	}
+	return robotsPolicyIgnore
+}
+
+func (p *CrawlerProcess) parseGoqueryRobots(body *goquery.Document) []string {
+	values := make([]string, 0)
+	body.Find("meta[name]").Each(func(i int, s *goquery.Selection) {
+		if strings.EqualFold(s.AttrOr("name", ""), "robots") {
+			values = append(values, s.AttrOr("content", ""))
+		}
+	})
+	return parseRobotsDirectives(values...)
+}
+
+// getPageRobots merges meta robots and X-Robots-Tag directives
+func getPageRobots(statusCode int, header http.Header, metaDirectives []string) *pageRobots {
+	directives := append(parseRobotsDirectives(header["X-Robots-Tag"]...), metaDirectives...)
+	res := &pageRobots{
+		Directives: []string{},
+		Indexable:  statusCode == http.StatusOK,
+		Follow:     true,
+	}
+
+	seen := make(map[string]bool)
+	for _, d := range directives {
+		if seen[d] {
+			continue
+		}
+		seen[d] = true
+		res.Directives = append(res.Directives, d)
+
+		switch d {
+		case "noindex":
+			res.Indexable = false
+		case "nofollow":
+			res.Follow = false
+		case "none":
+			res.Indexable = false
+			res.Follow = false
+		}
+	}
+
+	return res
+}
+
+// parseRobotsDirectives splits comma separated directives,
+// user agent prefix like `googlebot: noindex` is dropped
+func parseRobotsDirectives(values ...string) []string {
+	res := make([]string, 0)
+	for _, v := range values {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if i := strings.Index(d, ":"); i > 0 && !isRobotsDirectiveWithValue(d[:i]) {
+				d = strings.TrimSpace(d[i+1:])
+			}
+			if d != "" {
+				res = append(res, d)
+			}
+		}
+	}
+	return res
+}
+
+func isRobotsDirectiveWithValue(name string) bool {
+	switch name {
+	case "unavailable_after", "max-snippet", "max-image-preview", "max-video-preview":
+		return true
+	}
+	return false
+}
+
+func (p *CrawlerProcess) getRobotsReport() *robotsReport {
+	// urls of bounded crawl are not kept for report
+	policy := p.robotsPolicy()
+	if policy == robotsPolicyIgnore || p.bounded() {
+		return nil
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	res := &robotsReport{
+		Policy:        policy,
+		NoindexPages:  []string{},
+		NofollowPages: []string{},
+		NofollowLinks: []string{},
+	}
+
+	add := func(l string, d crawlerLinkData) {
+		if d.Robots == nil {
+			return
+		}
+		if !d.Robots.Indexable && d.StatusCode == http.StatusOK {
+			res.NoindexPages = append(res.NoindexPages, l)
+		}
+		if !d.Robots.Follow {
+			res.NofollowPages = append(res.NofollowPages, l)
+		}
+	}
+	for l, d := range p.data {
+		add(l, d)
+	}
+	for l, d := range p.robots {
+		add(l, d)
+	}
+
+	for l := range p.nofollow {
+		res.NofollowLinks = append(res.NofollowLinks, l)
+	}
+
+	sort.Strings(res.NoindexPages)
+	sort.Strings(res.NofollowPages)
+	sort.Strings(res.NofollowLinks)
+
+	return res
+}
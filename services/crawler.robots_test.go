@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetPageRobots(t *testing.T) {
+	header := http.Header{}
+	header.Add("X-Robots-Tag", "googlebot: NoIndex, max-snippet: 20")
+
+	robots := getPageRobots(http.StatusOK, header, parseRobotsDirectives("nofollow"))
+	if robots.Indexable || robots.Follow {
+		t.Errorf("page should be noindex and nofollow, got %+v", robots)
+	}
+
+	expected := []string{"noindex", "max-snippet: 20", "nofollow"}
+	if len(robots.Directives) != len(expected) {
+		t.Fatalf("expected directives %v, got %v", expected, robots.Directives)
+	}
+	for i := range expected {
+		if robots.Directives[i] != expected[i] {
+			t.Errorf("expected directive %s, got %s", expected[i], robots.Directives[i])
+		}
+	}
+
+	robots = getPageRobots(http.StatusNotFound, http.Header{}, nil)
+	if robots.Indexable || !robots.Follow {
+		t.Errorf("not found page should not be indexable, got %+v", robots)
+	}
+}
+
+func TestRegexParsingNofollow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>page</title></head><body><a href="/a">a</a><a rel="NoFollow" href="/hidden">h</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	conf := config.CrawlerConfig{Depth: 3, Workers: 2, UseRegexForParsing: true, RobotsPolicy: robotsPolicyRespect}
+	s := newTestCrawlerService(srv, conf)
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+
+	if _, ok := res.Pages[srv.URL+"/hidden"]; ok {
+		t.Error("nofollow link should not be crawled with regex parsing")
+	}
+	if len(res.Robots.NofollowLinks) != 1 || res.Robots.NofollowLinks[0] != srv.URL+"/hidden" {
+		t.Errorf("expected reported nofollow link /hidden, got %v", res.Robots.NofollowLinks)
+	}
+}
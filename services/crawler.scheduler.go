@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"go-link-crawler/log"
+	"sync"
+)
+
+// scheduler shares global budget of concurrent requests among hosts in round-robin order
+// and limits count of sites crawled at once, it is shared by all crawl processes
+type scheduler struct {
+	limited bool
+	slots   int // free request slots
+	hosts   []string
+	waiters map[string][]chan struct{} // host -> waiting workers in fifo order
+	next    int                        // index of host in hosts which gets the next free slot
+
+	maxSites    int
+	activeSites int
+	pending     []*pendingSite // seeds waiting for free site slot
+
+	mux sync.Mutex
+}
+
+// pendingSite is start of seed waiting for free site slot
+type pendingSite struct {
+	start func()
+}
+
+func newScheduler(workers, maxSites int) *scheduler {
+	return &scheduler{
+		limited:  workers > 0,
+		slots:    workers,
+		waiters:  make(map[string][]chan struct{}),
+		maxSites: maxSites,
+	}
+}
+
+// acquire waits for request slot of host, every successful acquire has to be followed by release
+func (s *scheduler) acquire(ctx context.Context, host string) error {
+	if !s.limited {
+		return nil
+	}
+
+	s.mux.Lock()
+	if s.slots > 0 && len(s.hosts) == 0 {
+		s.slots--
+		s.mux.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	if _, ok := s.waiters[host]; !ok {
+		s.hosts = append(s.hosts, host)
+	}
+	s.waiters[host] = append(s.waiters[host], ready)
+	s.mux.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for i, w := range s.waiters[host] {
+		if w == ready {
+			s.removeWaiter(host, i)
+			return ctx.Err()
+		}
+	}
+
+	// slot was granted while context was canceled
+	s.releaseLocked()
+	return ctx.Err()
+}
+
+func (s *scheduler) release() {
+	if !s.limited {
+		return
+	}
+
+	s.mux.Lock()
+	s.releaseLocked()
+	s.mux.Unlock()
+}
+
+// releaseLocked passes slot to the first waiter of the next host
+func (s *scheduler) releaseLocked() {
+	if len(s.hosts) == 0 {
+		s.slots++
+		return
+	}
+
+	if s.next >= len(s.hosts) {
+		s.next = 0
+	}
+	host := s.hosts[s.next]
+	ready := s.waiters[host][0]
+	if !s.removeWaiter(host, 0) {
+		s.next++
+	}
+	close(ready)
+}
+
+// removeWaiter removes i-th waiter of host, it returns true if host has no more waiters and is removed from round
+func (s *scheduler) removeWaiter(host string, i int) bool {
+	waiters := s.waiters[host]
+	waiters = append(waiters[:i], waiters[i+1:]...)
+	if len(waiters) > 0 {
+		s.waiters[host] = waiters
+		return false
+	}
+
+	delete(s.waiters, host)
+	for j, h := range s.hosts {
+		if h == host {
+			s.hosts = append(s.hosts[:j], s.hosts[j+1:]...)
+			if j < s.next {
+				s.next--
+			}
+			break
+		}
+	}
+	return true
+}
+
+// startSite runs start now or when some of active sites is finished, returned dequeue removes
+// waiting site from queue, it returns false if the site is started already
+func (s *scheduler) startSite(start func()) (dequeue func() bool) {
+	site := &pendingSite{start: start}
+	dequeue = func() bool {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+
+		for i, p := range s.pending {
+			if p == site {
+				s.pending = append(s.pending[:i], s.pending[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+
+	s.mux.Lock()
+	if s.maxSites > 0 && s.activeSites >= s.maxSites {
+		s.pending = append(s.pending, site)
+		log.WithTrace("CrawlerService", "scheduler", "startSite").Debugf("site is queued, active sites: %d, queued sites: %d", s.activeSites, len(s.pending))
+		s.mux.Unlock()
+		return dequeue
+	}
+	s.activeSites++
+	s.mux.Unlock()
+
+	start()
+	return dequeue
+}
+
+// finishSite starts the next queued site
+func (s *scheduler) finishSite() {
+	s.mux.Lock()
+	if len(s.pending) == 0 {
+		s.activeSites--
+		s.mux.Unlock()
+		return
+	}
+	site := s.pending[0]
+	s.pending = s.pending[1:]
+	s.mux.Unlock()
+
+	site.start()
+}
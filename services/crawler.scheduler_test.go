@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRoundRobin(t *testing.T) {
+	s := newScheduler(1, 0)
+	ctx := context.Background()
+	if err := s.acquire(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	granted := make(chan string, 3)
+	waiting := func(n int) {
+		for i := 0; i < 100; i++ {
+			s.mux.Lock()
+			count := 0
+			for _, w := range s.waiters {
+				count += len(w)
+			}
+			s.mux.Unlock()
+			if count == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("expected %d waiters", n)
+	}
+	for i, host := range []string{"a", "a", "b"} {
+		go func(host string) {
+			s.acquire(ctx, host)
+			granted <- host
+		}(host)
+		waiting(i + 1)
+	}
+
+	order := ""
+	for i := 0; i < 3; i++ {
+		s.release()
+		order += <-granted
+	}
+	if order != "aba" {
+		t.Errorf("expected round-robin order aba, got %s", order)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.acquire(canceled, "c"); err == nil {
+		t.Errorf("acquire should fail on canceled context")
+	}
+	s.release()
+	if s.slots != 1 || len(s.hosts) != 0 {
+		t.Errorf("expected 1 free slot without waiters, got %d slots, hosts %v", s.slots, s.hosts)
+	}
+}
+
+func TestSchedulerSites(t *testing.T) {
+	s := newScheduler(0, 1)
+	started := make([]int, 0)
+	s.startSite(func() { started = append(started, 1) })
+	s.startSite(func() { started = append(started, 2) })
+	if len(started) != 1 {
+		t.Fatalf("second site should be queued, started %v", started)
+	}
+
+	s.finishSite()
+	if len(started) != 2 || s.activeSites != 1 {
+		t.Errorf("queued site should start, started %v, active %d", started, s.activeSites)
+	}
+	s.finishSite()
+	if s.activeSites != 0 {
+		t.Errorf("expected no active sites, got %d", s.activeSites)
+	}
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/log"
+	"go-link-crawler/utils"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	securityMixedActive     = "mixed-active-content"
+	securityMixedPassive    = "mixed-passive-content"
+	securityInsecureForm    = "insecure-form"
+	securityInsecureLink    = "insecure-link"
+	securityNoHttpsRedirect = "no-https-redirect"
+)
+
+// pageResource is subresource reference of page
+type pageResource struct {
+	Url    string
+	Tag    string
+	Active bool // scripts, styles and frames can change the whole page
+}
+
+var resourceSelectors = []struct {
+	selector string
+	attr     string
+	active   bool
+}{
+	{"script[src]", "src", true},
+	{"iframe[src]", "src", true},
+	{"frame[src]", "src", true},
+	{"link[href]", "href", true}, // stylesheets only, see parseGoqueryResources
+	{"object[data]", "data", true},
+	{"embed[src]", "src", true},
+	{"img[src]", "src", false},
+	{"img[srcset]", "srcset", false},
+	{"source[src]", "src", false},
+	{"source[srcset]", "srcset", false},
+	{"audio[src]", "src", false},
+	{"video[src]", "src", false},
+	{"video[poster]", "poster", false},
+	{"track[src]", "src", false},
+	{"form[action]", "action", false},
+}
+
+type securityReport struct {
+	MixedContent   []crawlerFinding `json:"mixed_content"`
+	InsecureLinks  []crawlerFinding `json:"insecure_links"`
+	HttpsRedirects []crawlerFinding `json:"https_redirects"` // pages not redirected from http to https
+}
+
+func (p *CrawlerProcess) parseGoqueryResources(body *goquery.Document) []pageResource {
+	res := make([]pageResource, 0)
+	for _, rs := range resourceSelectors {
+		body.Find(rs.selector).Each(func(i int, s *goquery.Selection) {
+			tag := goquery.NodeName(s)
+			if tag == "link" && !hasRel(strings.ToLower(s.AttrOr("rel", "")), "stylesheet") {
+				return
+			}
+
+			value := s.AttrOr(rs.attr, "")
+			urls := []string{value}
+			if rs.attr == "srcset" {
+				urls = parseSrcset(value)
+			}
+			for _, u := range urls {
+				if u = strings.TrimSpace(u); u != "" {
+					res = append(res, pageResource{Url: u, Tag: tag, Active: rs.active})
+				}
+			}
+		})
+	}
+	return res
+}
+
+// parseSrcset returns urls of `img.png 1x, img-2x.png 2x`
+func parseSrcset(srcset string) []string {
+	res := make([]string, 0)
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			res = append(res, fields[0])
+		}
+	}
+	return res
+}
+
+func (p *CrawlerProcess) addSecurityFinding(check, severity, url, format string, args ...interface{}) {
+	p.mux.Lock()
+	p.securityFindings = append(p.securityFindings, crawlerFinding{
+		Check:    check,
+		Severity: severity,
+		Url:      url,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	p.mux.Unlock()
+}
+
+// checkMixedContent reports http resources of https page
+func (p *CrawlerProcess) checkMixedContent(pageUrl string, resources []pageResource) {
+	if !strings.HasPrefix(pageUrl, "https://") {
+		return
+	}
+
+	for _, r := range resources {
+		full := utils.RelativeUrlToFull(r.Url, pageUrl, p.uri)
+		if !strings.HasPrefix(full, "http://") {
+			continue
+		}
+
+		switch {
+		case r.Tag == "form":
+			p.addSecurityFinding(securityInsecureForm, severityError, pageUrl, "form is submitted to %s", full)
+		case r.Active:
+			p.addSecurityFinding(securityMixedActive, severityError, pageUrl, "%s tag loads %s", r.Tag, full)
+		default:
+			p.addSecurityFinding(securityMixedPassive, severityWarning, pageUrl, "%s tag loads %s", r.Tag, full)
+		}
+	}
+}
+
+// checkInsecureLink reports link from https page to http inner page
+func (p *CrawlerProcess) checkInsecureLink(pageUrl, target string) {
+	if !p.crawlerService.conf.Security.MixedContent {
+		return
+	}
+	if strings.HasPrefix(pageUrl, "https://") && strings.HasPrefix(target, "http://") {
+		p.addSecurityFinding(securityInsecureLink, severityWarning, pageUrl, "link to http page %s", target)
+	}
+}
+
+// checkHttpsRedirect reports http page which is not redirected to https
+func (p *CrawlerProcess) checkHttpsRedirect(requestedUrl, finalUrl string) {
+	if strings.HasPrefix(requestedUrl, "http://") && strings.HasPrefix(finalUrl, "http://") {
+		p.addSecurityFinding(securityNoHttpsRedirect, severityError, requestedUrl, "http page is not redirected to https")
+	}
+}
+
+// queueHttpsProbe queues probe of http version of the first https page of each host
+func (p *CrawlerProcess) queueHttpsProbe(pageUrl string) {
+	u, err := url.Parse(pageUrl)
+	if err != nil || u.Scheme != "https" {
+		return
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.httpsProbed[u.Host] {
+		return
+	}
+	p.httpsProbed[u.Host] = true
+	p.httpsProbes = append(p.httpsProbes, pageUrl)
+}
+
+// runHttpsProbes sends queued probes, each probe takes request slot of crawl and site like a link
+func (p *CrawlerProcess) runHttpsProbes() {
+	for {
+		p.mux.Lock()
+		if len(p.httpsProbes) == 0 {
+			p.mux.Unlock()
+			return
+		}
+		pageUrl := p.httpsProbes[0]
+		p.httpsProbes = p.httpsProbes[1:]
+		p.mux.Unlock()
+
+		if err := p.limiter.acquire(p.ctx); err != nil {
+			return
+		}
+		if err := p.crawlerService.scheduler.acquire(p.ctx, p.uri.Host); err != nil {
+			p.limiter.release()
+			return
+		}
+		p.probeHttpsRedirect(pageUrl)
+		p.crawlerService.scheduler.release()
+		p.limiter.release()
+	}
+}
+
+// probeHttpsRedirect requests http version of https page by HEAD, request is aborted by stop of crawl
+func (p *CrawlerProcess) probeHttpsRedirect(pageUrl string) {
+	u, err := url.Parse(pageUrl)
+	if err != nil {
+		return
+	}
+	u.Scheme = "http"
+
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return
+	}
+
+	res, err := p.crawlerService.httpClient.Do(req.WithContext(p.requestCtx))
+	if err != nil {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "probeHttpsRedirect").Debugf("http version of %s err: %v", pageUrl, err)
+		return
+	}
+	res.Body.Close()
+
+	p.checkHttpsRedirect(u.String(), res.Request.URL.String())
+}
+
+func (p *CrawlerProcess) getSecurityReport() *securityReport {
+	if !p.crawlerService.conf.Security.MixedContent {
+		return nil
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	res := &securityReport{
+		MixedContent:   []crawlerFinding{},
+		InsecureLinks:  []crawlerFinding{},
+		HttpsRedirects: []crawlerFinding{},
+	}
+
+	seen := make(map[crawlerFinding]bool)
+	for _, f := range p.securityFindings {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+
+		switch f.Check {
+		case securityInsecureLink:
+			res.InsecureLinks = append(res.InsecureLinks, f)
+		case securityNoHttpsRedirect:
+			res.HttpsRedirects = append(res.HttpsRedirects, f)
+		default:
+			res.MixedContent = append(res.MixedContent, f)
+		}
+	}
+
+	sortFindings(res.MixedContent)
+	sortFindings(res.InsecureLinks)
+	sortFindings(res.HttpsRedirects)
+
+	return res
+}
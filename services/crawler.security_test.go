@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/config"
+	"io/ioutil"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseGoqueryResources(t *testing.T) {
+	html := `<html><head><link rel="stylesheet" href="http://cdn/a.css"><link rel="icon" href="http://cdn/i.ico"></head>
+		<body><script src="/app.js"></script><img srcset="a.png 1x, http://cdn/b.png 2x"><form action="http://site/post"></form></body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := (&CrawlerProcess{}).parseGoqueryResources(doc)
+	expected := []pageResource{
+		{Url: "/app.js", Tag: "script", Active: true},
+		{Url: "http://cdn/a.css", Tag: "link", Active: true},
+		{Url: "a.png", Tag: "img"},
+		{Url: "http://cdn/b.png", Tag: "img"},
+		{Url: "http://site/post", Tag: "form"},
+	}
+	if fmt.Sprint(resources) != fmt.Sprint(expected) {
+		t.Errorf("expected resources %v, got %v", expected, resources)
+	}
+}
+
+func TestSecurityReport(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprintf(w, `<html><body><script src="http://cdn.example/x.js"></script><img src="http://cdn.example/y.png">
+				<form action="http://%s/post"></form><a href="http://%s/plain">plain</a><a href="/secure">secure</a></body></html>`, r.Host, r.Host)
+			return
+		}
+		fmt.Fprint(w, `<html><body></body></html>`)
+	}))
+	// http probes of tls server fail by design
+	srv.Config.ErrorLog = stdlog.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	conf := config.CrawlerConfig{Depth: 2, Workers: 1}
+	conf.Security.MixedContent = true
+	s := newTestCrawlerService(srv, conf)
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+
+	checks := func(findings []crawlerFinding) []string {
+		res := make([]string, 0, len(findings))
+		for _, f := range findings {
+			res = append(res, strings.TrimPrefix(strings.TrimPrefix(f.Url, srv.URL), "http://"+srv.Listener.Addr().String())+" "+f.Check)
+		}
+		return res
+	}
+
+	expected := []string{"/ insecure-form", "/ mixed-active-content", "/ mixed-passive-content"}
+	if got := checks(res.Security.MixedContent); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected mixed content %v, got %v", expected, got)
+	}
+	if got := checks(res.Security.InsecureLinks); len(got) != 1 || got[0] != "/ insecure-link" {
+		t.Errorf("expected insecure link of /, got %v", got)
+	}
+	// http version is probed once per host, tls server does not redirect it
+	expected = []string{"/ no-https-redirect", "/plain no-https-redirect"}
+	if got := checks(res.Security.HttpsRedirects); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected https redirects %v, got %v", expected, got)
+	}
+}
+
+func TestQueueHttpsProbe(t *testing.T) {
+	p := &CrawlerProcess{httpsProbed: make(map[string]bool)}
+	for _, u := range []string{"http://site.com/", "https://site.com/", "https://site.com/a", "https://www.site.com/a"} {
+		p.queueHttpsProbe(u)
+	}
+	if strings.Join(p.httpsProbes, ",") != "https://site.com/,https://www.site.com/a" {
+		t.Errorf("the first https page of each host should be probed, got %v", p.httpsProbes)
+	}
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-link-crawler/utils"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ScopeHost keeps crawl on host of seed, www. prefix is ignored
+	ScopeHost = "host"
+	// ScopeDomain allows subdomains of seed host
+	ScopeDomain = "domain"
+	// ScopePrefix keeps crawl under path of seed url
+	ScopePrefix = "prefix"
+)
+
+// formats of seed lists
+const (
+	SeedsText  = "text"
+	SeedsCsv   = "csv"
+	SeedsJsonl = "jsonl"
+)
+
+// Seed is a root url of crawl with own options, zero values mean options of config
+type Seed struct {
+	Url   string   `json:"url"`
+	Depth int      `json:"depth,omitempty"`
+	Scope string   `json:"scope,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// SeedsFormat returns format of seed list by file extension
+func SeedsFormat(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return SeedsCsv
+	case ".jsonl", ".ndjson":
+		return SeedsJsonl
+	}
+	return SeedsText
+}
+
+// ParseSeeds reads seeds in format text (url per line), csv (url,depth,scope,tags with optional header,
+// tags are separated by |) or jsonl. Empty lines and lines starting with # are skipped.
+// Invalid and duplicate lines are returned as errors with line numbers, valid seeds are returned anyway,
+// seeds which differ only by scheme or www. prefix of host are duplicates.
+func ParseSeeds(r io.Reader, format string) ([]Seed, []error) {
+	seeds := make([]Seed, 0)
+	errs := make([]error, 0)
+	lines := make(map[string]int) // key of seed -> line of the first seed
+
+	scanner := bufio.NewScanner(r)
+	n := 0
+	first := true // csv header can be only the first line which is not skipped
+	for scanner.Scan() {
+		n++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		seed, err := parseSeedLine(line, format, first)
+		first = false
+		if err == nil {
+			err = normalizeSeed(&seed)
+		}
+		if err == errSeedHeader {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %v", n, err))
+			continue
+		}
+
+		key := seedKey(seed.Url)
+		if prev, ok := lines[key]; ok {
+			errs = append(errs, fmt.Errorf("line %d: duplicate of seed on line %d: %s", n, prev, seed.Url))
+			continue
+		}
+		lines[key] = n
+		seeds = append(seeds, seed)
+	}
+
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("line %d: %v", n+1, err))
+	}
+
+	return seeds, errs
+}
+
+var errSeedHeader = fmt.Errorf("csv header")
+
+// seedKey returns normalized url of seed without scheme and www. prefix of host,
+// seeds with the same key crawl the same pages
+func seedKey(seedUrl string) string {
+	u, err := url.Parse(seedUrl)
+	if err != nil {
+		return seedUrl
+	}
+	return strings.TrimPrefix(u.Host, "www.") + u.RequestURI()
+}
+
+func parseSeedLine(line, format string, first bool) (Seed, error) {
+	seed := Seed{}
+	switch format {
+	case SeedsText:
+		seed.Url = line
+	case SeedsJsonl:
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&seed); err != nil {
+			return seed, fmt.Errorf("invalid json: %v", err)
+		}
+	case SeedsCsv:
+		fields, err := csv.NewReader(strings.NewReader(line)).Read()
+		if err != nil {
+			return seed, fmt.Errorf("invalid csv: %v", err)
+		}
+		if first && strings.EqualFold(strings.TrimSpace(fields[0]), "url") {
+			return seed, errSeedHeader
+		}
+		if len(fields) > 4 {
+			return seed, fmt.Errorf("expected url,depth,scope,tags, got %d fields", len(fields))
+		}
+		seed.Url = fields[0]
+		if len(fields) > 1 && strings.TrimSpace(fields[1]) != "" {
+			if seed.Depth, err = strconv.Atoi(strings.TrimSpace(fields[1])); err != nil {
+				return seed, fmt.Errorf("invalid depth %q", fields[1])
+			}
+		}
+		if len(fields) > 2 {
+			seed.Scope = fields[2]
+		}
+		if len(fields) > 3 {
+			for _, tag := range strings.Split(fields[3], "|") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					seed.Tags = append(seed.Tags, tag)
+				}
+			}
+		}
+	default:
+		return seed, fmt.Errorf("unknown seeds format: %s", format)
+	}
+	return seed, nil
+}
+
+// normalizeSeed validates seed, bare domains get https scheme and empty path gets /
+func normalizeSeed(seed *Seed) error {
+	raw := strings.TrimSpace(seed.Url)
+	if raw == "" {
+		return fmt.Errorf("empty url")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %v", seed.Url, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url %q: scheme should be http or https", seed.Url)
+	}
+	if u.Host == "" || strings.ContainsAny(u.Host, " \t") {
+		return fmt.Errorf("invalid url %q: host is missing", seed.Url)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	seed.Url = u.String()
+
+	if seed.Depth < 0 {
+		return fmt.Errorf("invalid depth %d", seed.Depth)
+	}
+
+	seed.Scope = strings.ToLower(strings.TrimSpace(seed.Scope))
+	switch seed.Scope {
+	case "", ScopeHost, ScopeDomain, ScopePrefix:
+	default:
+		return fmt.Errorf("invalid scope %q, use host, domain or prefix", seed.Scope)
+	}
+
+	return nil
+}
+
+// maxDepth returns depth of seed or depth of config
+func (p *CrawlerProcess) maxDepth() int {
+	if p.seed.Depth > 0 {
+		return p.seed.Depth
+	}
+	return p.crawlerService.conf.Depth
+}
+
+// inScope checks that url belongs to crawled site by scope of seed
+func (p *CrawlerProcess) inScope(fullUrl string) bool {
+	switch p.seed.Scope {
+	case ScopeDomain:
+		u, err := url.Parse(fullUrl)
+		if err != nil {
+			return false
+		}
+		host := strings.TrimPrefix(u.Host, "www.")
+		return host == p.uri.Host || strings.HasSuffix(host, "."+p.uri.Host)
+	case ScopePrefix:
+		u, err := url.Parse(fullUrl)
+		if err != nil {
+			return false
+		}
+		// /docs/ and /docs/index.html are prefix /docs/
+		prefix := p.uri.Path[:strings.LastIndex(p.uri.Path, "/")+1]
+		return utils.IsInnerUrl(fullUrl, p.uri) && strings.HasPrefix(u.Path, prefix)
+	}
+	return utils.IsInnerUrl(fullUrl, p.uri)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-link-crawler/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSeeds(t *testing.T) {
+	csv := "url,depth,scope,tags\r\n" +
+		"example.com,2,domain,news|daily\r\n" +
+		"\r\n" +
+		"# comment\r\n" +
+		"https://example.com/,,,\r\n" +
+		"ftp://example.com\r\n" +
+		"https://docs.example.com/guide/,1,prefix\r\n" +
+		"https://blog.example.com,x\r\n"
+
+	seeds, errs := ParseSeeds(strings.NewReader(csv), SeedsCsv)
+	expected := []Seed{
+		{Url: "https://example.com/", Depth: 2, Scope: ScopeDomain, Tags: []string{"news", "daily"}},
+		{Url: "https://docs.example.com/guide/", Depth: 1, Scope: ScopePrefix},
+	}
+	if !reflect.DeepEqual(seeds, expected) {
+		t.Errorf("expected seeds %+v, got %+v", expected, seeds)
+	}
+
+	expectedErrs := []string{"line 5: duplicate", "line 6: invalid url", "line 8: invalid depth"}
+	if len(errs) != len(expectedErrs) {
+		t.Fatalf("expected errors %v, got %v", expectedErrs, errs)
+	}
+	for i, err := range errs {
+		if !strings.HasPrefix(err.Error(), expectedErrs[i]) {
+			t.Errorf("expected error %q, got %q", expectedErrs[i], err)
+		}
+	}
+
+	// header after comment and seeds differing only by www. prefix
+	csv = "# seeds\n\nurl,depth\nexample.org,1\nhttp://www.example.org/\n"
+	seeds, errs = ParseSeeds(strings.NewReader(csv), SeedsCsv)
+	if len(seeds) != 1 || seeds[0].Url != "https://example.org/" {
+		t.Errorf("header should be skipped, got seeds %+v", seeds)
+	}
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "line 5: duplicate of seed on line 4") {
+		t.Errorf("www. seed should be duplicate, got %v", errs)
+	}
+
+	seeds, errs = ParseSeeds(strings.NewReader(`{"url": "http://example.org", "tags": ["a"]}`+"\n"+`{"link": "x"}`), SeedsJsonl)
+	if len(seeds) != 1 || seeds[0].Url != "http://example.org/" || len(errs) != 1 {
+		t.Errorf("unexpected jsonl seeds %+v, errors %v", seeds, errs)
+	}
+}
+
+func TestDomainScopeSubdomainLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Host + r.URL.Path {
+		case "site.test/":
+			fmt.Fprint(w, `<a href="http://blog.site.test/post">blog</a><a href="http://other.test/">other</a>`)
+		case "blog.site.test/post":
+			fmt.Fprint(w, `<a href="/about">about</a>`)
+		case "blog.site.test/about":
+			fmt.Fprint(w, `about blog`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	s := newTestCrawlerService(srv, config.CrawlerConfig{Depth: 3, Workers: 2})
+	defer s.Close()
+	// every host is served by test server
+	s.httpClient = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+	}}
+
+	p, err := s.StartSeed(Seed{Url: "http://site.test/", Scope: ScopeDomain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+
+	expected := map[string]int{
+		"http://site.test/":           200,
+		"http://blog.site.test/post":  200,
+		"http://blog.site.test/about": 200,
+	}
+	if len(res.Pages) != len(expected) {
+		t.Errorf("expected pages %v, got %v", expected, res.Pages)
+	}
+	for l, status := range expected {
+		if res.Pages[l].StatusCode != status {
+			t.Errorf("page %s expected status %d, got %+v", l, status, res.Pages[l])
+		}
+	}
+}
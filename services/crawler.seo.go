@@ -0,0 +1,172 @@
+package services
+
+import (
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/utils"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultTitleMaxLength       = 60
+	defaultDescriptionMaxLength = 160
+)
+
+// pageMeta is SEO related page data
+type pageMeta struct {
+	Description      string            `json:"description,omitempty"`
+	Canonical        string            `json:"canonical,omitempty"`
+	Robots           string            `json:"robots,omitempty"`
+	RobotsHeader     string            `json:"robots_header,omitempty"` // X-Robots-Tag of response
+	H1               []string          `json:"h1,omitempty"`
+	Hreflang         map[string]string `json:"hreflang,omitempty"`
+	OpenGraph        map[string]string `json:"open_graph,omitempty"`
+	ImagesWithoutAlt []string          `json:"images_without_alt,omitempty"`
+}
+
+func (p *CrawlerProcess) parseGoqueryMeta(body *goquery.Document) *pageMeta {
+	meta := &pageMeta{
+		H1:        []string{},
+		Hreflang:  map[string]string{},
+		OpenGraph: map[string]string{},
+	}
+
+	body.Find("meta").Each(func(i int, s *goquery.Selection) {
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		name := strings.ToLower(s.AttrOr("name", ""))
+		switch name {
+		case "description":
+			meta.Description = content
+		case "robots":
+			meta.Robots = strings.ToLower(content)
+		}
+
+		if property := strings.ToLower(s.AttrOr("property", "")); strings.HasPrefix(property, "og:") {
+			meta.OpenGraph[property] = content
+		}
+	})
+
+	body.Find("link[rel]").Each(func(i int, s *goquery.Selection) {
+		rel := strings.ToLower(s.AttrOr("rel", ""))
+		href := strings.TrimSpace(s.AttrOr("href", ""))
+		if hasRel(rel, "canonical") && meta.Canonical == "" {
+			meta.Canonical = href
+		}
+		if lang, ok := s.Attr("hreflang"); ok && hasRel(rel, "alternate") {
+			meta.Hreflang[strings.ToLower(lang)] = href
+		}
+	})
+
+	body.Find("h1").Each(func(i int, s *goquery.Selection) {
+		meta.H1 = append(meta.H1, strings.Join(strings.Fields(s.Text()), " "))
+	})
+
+	body.Find("img").Each(func(i int, s *goquery.Selection) {
+		if _, ok := s.Attr("alt"); !ok {
+			meta.ImagesWithoutAlt = append(meta.ImagesWithoutAlt, s.AttrOr("src", ""))
+		}
+	})
+
+	return meta
+}
+
+// getSeoFindings audits meta data of crawled pages
+func (p *CrawlerProcess) getSeoFindings() []crawlerFinding {
+	conf := p.crawlerService.conf.Seo
+	if !conf.Enabled {
+		return nil
+	}
+
+	titleMaxLength := conf.TitleMaxLength
+	if titleMaxLength <= 0 {
+		titleMaxLength = defaultTitleMaxLength
+	}
+	descriptionMaxLength := conf.DescriptionMaxLength
+	if descriptionMaxLength <= 0 {
+		descriptionMaxLength = defaultDescriptionMaxLength
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	findings := make([]crawlerFinding, 0)
+	add := func(check, severity, url, format string, args ...interface{}) {
+		findings = append(findings, crawlerFinding{
+			Check:    check,
+			Severity: severity,
+			Url:      url,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	titles := make(map[string][]string)
+	descriptions := make(map[string][]string)
+	for l, d := range p.data {
+		// error pages are reported as broken links, their content is not audited
+		if d.Meta == nil || d.StatusCode != http.StatusOK {
+			continue
+		}
+
+		title := strings.TrimSpace(d.Title)
+		if title == "" {
+			add("title-missing", severityError, l, "page has no title")
+		} else {
+			titles[title] = append(titles[title], l)
+			if n := utf8.RuneCountInString(title); n > titleMaxLength {
+				add("title-too-long", severityWarning, l, "title is %d characters long, max is %d", n, titleMaxLength)
+			}
+		}
+
+		if d.Meta.Description == "" {
+			add("description-missing", severityWarning, l, "page has no meta description")
+		} else {
+			descriptions[d.Meta.Description] = append(descriptions[d.Meta.Description], l)
+			if n := utf8.RuneCountInString(d.Meta.Description); n > descriptionMaxLength {
+				add("description-too-long", severityNotice, l, "meta description is %d characters long, max is %d", n, descriptionMaxLength)
+			}
+		}
+
+		if len(d.Meta.H1) > 1 {
+			add("multiple-h1", severityWarning, l, "page has %d h1 headings", len(d.Meta.H1))
+		}
+
+		// canonicals which are not ok are reported by canonical report
+		if d.Meta.Canonical != "" {
+			if canonical := utils.RelativeUrlToFull(d.Meta.Canonical, l, p.uri); canonical != l {
+				add("canonical-elsewhere", severityNotice, l, "canonical points to %s", canonical)
+			}
+		}
+
+		if p.listed[l] {
+			for _, directive := range parseRobotsDirectives(d.Meta.Robots, d.Meta.RobotsHeader) {
+				if directive == "noindex" || directive == "none" {
+					add("noindex-in-sitemap", severityError, l, "noindex page is listed in sitemap")
+					break
+				}
+			}
+		}
+
+		if n := len(d.Meta.ImagesWithoutAlt); n > 0 {
+			add("image-alt-missing", severityWarning, l, "%d images without alt: %s", n, strings.Join(d.Meta.ImagesWithoutAlt, ", "))
+		}
+	}
+
+	addDuplicates := func(check string, groups map[string][]string) {
+		for value, urls := range groups {
+			if len(urls) < 2 {
+				continue
+			}
+			for _, l := range urls {
+				add(check, severityWarning, l, "%q is shared by %d pages", value, len(urls))
+			}
+		}
+	}
+	addDuplicates("title-duplicate", titles)
+	addDuplicates("description-duplicate", descriptions)
+
+	sortFindings(findings)
+
+	return findings
+}
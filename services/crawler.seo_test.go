@@ -0,0 +1,74 @@
+package services
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"go-link-crawler/config"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseGoqueryMeta(t *testing.T) {
+	html := `<html><head>
+		<meta name="Description" content=" About us ">
+		<meta name="robots" content="NoIndex">
+		<meta property="og:title" content="Us">
+		<link rel="canonical" href="/about"><link rel="canonical" href="/other">
+		<link rel="alternate" hreflang="DE" href="/de/about">
+	</head><body><h1>About  <b>us</b></h1><h1>Team</h1><img src="/a.png"><img src="/b.png" alt=""></body></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := (&CrawlerProcess{}).parseGoqueryMeta(doc)
+	if meta.Description != "About us" || meta.Robots != "noindex" || meta.Canonical != "/about" {
+		t.Errorf("unexpected meta %+v", meta)
+	}
+	if meta.OpenGraph["og:title"] != "Us" || meta.Hreflang["de"] != "/de/about" {
+		t.Errorf("unexpected open graph %v or hreflang %v", meta.OpenGraph, meta.Hreflang)
+	}
+	if strings.Join(meta.H1, "|") != "About us|Team" {
+		t.Errorf("unexpected h1 %v", meta.H1)
+	}
+	if len(meta.ImagesWithoutAlt) != 1 || meta.ImagesWithoutAlt[0] != "/a.png" {
+		t.Errorf("unexpected images without alt %v", meta.ImagesWithoutAlt)
+	}
+}
+
+func TestSeoFindings(t *testing.T) {
+	conf := config.CrawlerConfig{}
+	conf.Seo.Enabled = true
+	conf.Seo.TitleMaxLength = 10
+	uri, _ := url.Parse("https://site.com/")
+	p := &CrawlerProcess{
+		crawlerService: &CrawlerService{conf: conf},
+		uri:            uri,
+		listed:         map[string]bool{"https://site.com/b": true, "https://site.com/c": true},
+		data: map[string]crawlerLinkData{
+			"https://site.com/a":    {Title: "Same", StatusCode: 200, Meta: &pageMeta{Description: "a", Canonical: "/gone"}},
+			"https://site.com/b":    {Title: "Same", StatusCode: 200, Meta: &pageMeta{Description: "b", Robots: "noindex", H1: []string{"x", "y"}}},
+			"https://site.com/c":    {Title: "Very long title", StatusCode: 200, Meta: &pageMeta{RobotsHeader: "googlebot: none"}},
+			"https://site.com/gone": {StatusCode: 404, Meta: &pageMeta{}},
+		},
+	}
+
+	findings := p.getSeoFindings()
+	checks := make([]string, 0, len(findings))
+	for _, f := range findings {
+		checks = append(checks, strings.TrimPrefix(f.Url, "https://site.com")+" "+f.Check)
+	}
+	expected := []string{
+		"/a canonical-elsewhere",
+		"/a title-duplicate",
+		"/b multiple-h1",
+		"/b noindex-in-sitemap",
+		"/b title-duplicate",
+		"/c description-missing",
+		"/c noindex-in-sitemap",
+		"/c title-too-long",
+	}
+	if strings.Join(checks, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected findings %v, got %v", expected, checks)
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"go-link-crawler/log"
+	"go-link-crawler/utils"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	// sitemap index files could refer each other, so nesting is limited
+	sitemapMaxNesting = 3
+	sitemapMaxFiles   = 1000
+	// sitemapMaxSize is max size of uncompressed sitemap by sitemaps.org protocol,
+	// it bounds gzip decompression too
+	sitemapMaxSize = 50 * 1024 * 1024
+)
+
+// sitemapDocument covers both <urlset> and <sitemapindex> root elements
+type sitemapDocument struct {
+	XMLName  xml.Name
+	Urls     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapReport struct {
+	Files         []string `json:"files"`
+	ListedCount   int      `json:"listed_count"`
+	OrphanPages   []string `json:"orphan_pages"`   // listed in sitemap but not linked from crawled pages
+	UnlistedPages []string `json:"unlisted_pages"` // linked from crawled pages but missing in sitemap
+}
+
+// processSitemapLinks puts urls from robots.txt sitemaps and /sitemap.xml to the crawl queue
+func (p *CrawlerProcess) processSitemapLinks(link crawlerLink) {
+	root, err := url.Parse(link.Url)
+	if err != nil {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "processSitemapLinks").Errorf("url.Parse link: %s err: %v", link.Url, err)
+		return
+	}
+
+	p.mux.Lock()
+	p.linked[link.Url] = true
+	p.mux.Unlock()
+
+	files := p.robotsSitemaps(root)
+	files = append(files, fmt.Sprintf("%s://%s/sitemap.xml", root.Scheme, root.Host))
+
+	maxUrls := p.crawlerService.conf.Sitemap.MaxUrls
+	walk := &sitemapWalk{files: make(map[string]bool), urls: make(map[string]bool), budget: -1}
+	if maxUrls > 0 {
+		p.mux.RLock()
+		walk.budget = maxUrls - len(p.listed)
+		p.mux.RUnlock()
+	}
+
+	listed := make([]string, 0)
+	for _, f := range files {
+		listed = append(listed, p.fetchSitemap(f, 0, walk)...)
+	}
+	if walk.budget == 0 {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "processSitemapLinks").Warnf("sitemap urls limit %d reached on %s", maxUrls, link.Url)
+	}
+
+	for _, l := range listed {
+		p.mux.Lock()
+		p.listed[l] = true
+		p.mux.Unlock()
+
+		if p.pushLink(l, "", link.Depth+1) {
+			log.WithTrace("CrawlerService", "CrawlerProcess", "processSitemapLinks").Tracef("new sitemap link found: %s", l)
+		}
+	}
+}
+
+// robotsSitemaps returns urls from `Sitemap:` directives of robots.txt
+func (p *CrawlerProcess) robotsSitemaps(root *url.URL) []string {
+	res := make([]string, 0)
+	robotsUrl := fmt.Sprintf("%s://%s/robots.txt", root.Scheme, root.Host)
+
+	body, err := p.fetchFile(robotsUrl)
+	if err != nil {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "robotsSitemaps").Debugf("robots.txt %s err: %v", robotsUrl, err)
+		return res
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 8 && strings.EqualFold(line[:8], "sitemap:") {
+			res = append(res, strings.TrimSpace(line[8:]))
+		}
+	}
+
+	return res
+}
+
+// sitemapWalk is state of fetching sitemaps of site, budget is number of urls which could still be collected,
+// negative budget means no limit
+type sitemapWalk struct {
+	files  map[string]bool
+	urls   map[string]bool
+	budget int
+}
+
+// fetchSitemap returns new in scope page urls of sitemap, sitemap indexes are followed recursively,
+// no more files are fetched when budget of walk is exhausted
+func (p *CrawlerProcess) fetchSitemap(sitemapUrl string, nesting int, walk *sitemapWalk) []string {
+	res := make([]string, 0)
+	if walk.budget == 0 || walk.files[sitemapUrl] || len(walk.files) >= sitemapMaxFiles {
+		return res
+	}
+	walk.files[sitemapUrl] = true
+
+	body, err := p.fetchFile(sitemapUrl)
+	if err != nil {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "fetchSitemap").Debugf("sitemap %s err: %v", sitemapUrl, err)
+		return res
+	}
+
+	doc := sitemapDocument{}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "fetchSitemap").Warnf("xml.Unmarshal sitemap: %s err: %v", sitemapUrl, err)
+		return res
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "fetchSitemap").Debugf("sitemap %s has unexpected root element <%s>", sitemapUrl, doc.XMLName.Local)
+		return res
+	}
+
+	p.mux.Lock()
+	p.sitemapFiles = append(p.sitemapFiles, sitemapUrl)
+	p.mux.Unlock()
+
+	for _, u := range doc.Urls {
+		if walk.budget == 0 {
+			return res
+		}
+		loc := p.normalizeSitemapUrl(u.Loc, sitemapUrl)
+		if loc == "" || walk.urls[loc] || !p.inScope(loc) {
+			continue
+		}
+		walk.urls[loc] = true
+		if walk.budget > 0 {
+			walk.budget--
+		}
+		res = append(res, loc)
+	}
+
+	if nesting < sitemapMaxNesting {
+		for _, s := range doc.Sitemaps {
+			if loc := p.normalizeSitemapUrl(s.Loc, sitemapUrl); loc != "" {
+				res = append(res, p.fetchSitemap(loc, nesting+1, walk)...)
+			}
+		}
+	}
+
+	return res
+}
+
+// normalizeSitemapUrl resolves url of sitemap the same way as links of pages, fragment is dropped,
+// it returns empty string for urls which are not http
+func (p *CrawlerProcess) normalizeSitemapUrl(loc, sitemapUrl string) string {
+	loc = strings.TrimSpace(loc)
+	if loc == "" {
+		return ""
+	}
+	scheme := utils.GetUrlScheme(loc)
+	if scheme != "http" && scheme != "https" && scheme != "" {
+		return ""
+	}
+
+	fullUrl, _ := utils.SplitFragment(utils.RelativeUrlToFull(loc, sitemapUrl, p.uri))
+	return fullUrl
+}
+
+// fetchFile requests file and unpacks it if it is gzipped, request is canceled by stop of crawl
+func (p *CrawlerProcess) fetchFile(fileUrl string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, fileUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.crawlerService.httpClient.Do(req.WithContext(p.ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	body, err := readLimited(res.Body, sitemapMaxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// gzip magic number, content type is not reliable for .xml.gz files
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+
+		return readLimited(gz, sitemapMaxSize)
+	}
+
+	return body, nil
+}
+
+// readLimited reads at most max bytes, larger content is an error
+func readLimited(r io.Reader, max int64) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > max {
+		return nil, fmt.Errorf("file is larger than %d bytes", max)
+	}
+	return body, nil
+}
+
+func (p *CrawlerProcess) getSitemapReport() *sitemapReport {
+	if !p.crawlerService.conf.Sitemap.Enabled {
+		return nil
+	}
+
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	res := &sitemapReport{
+		Files:         append([]string{}, p.sitemapFiles...),
+		ListedCount:   len(p.listed),
+		OrphanPages:   []string{},
+		UnlistedPages: []string{},
+	}
+
+	for l := range p.listed {
+		if !p.linked[l] {
+			res.OrphanPages = append(res.OrphanPages, l)
+		}
+	}
+
+	for l := range p.linked {
+		if !p.listed[l] {
+			res.UnlistedPages = append(res.UnlistedPages, l)
+		}
+	}
+
+	sort.Strings(res.OrphanPages)
+	sort.Strings(res.UnlistedPages)
+
+	return res
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"go-link-crawler/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSitemapReport(t *testing.T) {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "User-agent: *\nSitemap: %s/index.xml\n", srv.URL)
+	})
+	mux.HandleFunc("/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<?xml version="1.0"?><sitemapindex><sitemap><loc> /pages.xml.gz </loc></sitemap></sitemapindex>`)
+	})
+	mux.HandleFunc("/pages.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		gz := gzip.NewWriter(w)
+		fmt.Fprintf(gz, `<urlset><url><loc>%s/a#top</loc></url><url><loc>/orphan</loc></url><url><loc>mailto:a@site.com</loc></url></urlset>`, srv.URL)
+		gz.Close()
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><a href="/a">a</a><a href="/b">b</a></body></html>`)
+	})
+	srv = httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conf := config.CrawlerConfig{Depth: 3, Workers: 2}
+	conf.Sitemap.Enabled = true
+	s := &CrawlerService{
+		conf:       conf,
+		httpClient: srv.Client(),
+		scheduler:  newScheduler(0, 0),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+
+	report := res.SitemapXml
+	if len(report.Files) != 2 || report.ListedCount != 2 {
+		t.Fatalf("expected 2 sitemap files with 2 urls, got %+v", report)
+	}
+	if len(report.OrphanPages) != 1 || report.OrphanPages[0] != srv.URL+"/orphan" {
+		t.Errorf("expected orphan page /orphan, got %v", report.OrphanPages)
+	}
+	if len(report.UnlistedPages) != 2 || report.UnlistedPages[0] != srv.URL+"/" || report.UnlistedPages[1] != srv.URL+"/b" {
+		t.Errorf("expected unlisted pages / and /b, got %v", report.UnlistedPages)
+	}
+	if _, ok := res.Pages[srv.URL+"/orphan"]; !ok {
+		t.Error("orphan page should be crawled from sitemap")
+	}
+}
+
+func TestSitemapMaxUrls(t *testing.T) {
+	fetched := make(chan string, 10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<sitemapindex><sitemap><loc>/first.xml</loc></sitemap><sitemap><loc>/second.xml</loc></sitemap></sitemapindex>`)
+	})
+	mux.HandleFunc("/first.xml", func(w http.ResponseWriter, r *http.Request) {
+		fetched <- r.URL.Path
+		fmt.Fprint(w, `<urlset><url><loc>/a</loc></url><url><loc>/a#top</loc></url><url><loc>https://other.com/</loc></url><url><loc>/b</loc></url><url><loc>/c</loc></url></urlset>`)
+	})
+	mux.HandleFunc("/second.xml", func(w http.ResponseWriter, r *http.Request) {
+		fetched <- r.URL.Path
+		fmt.Fprint(w, `<urlset><url><loc>/d</loc></url></urlset>`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body></body></html>`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	conf := config.CrawlerConfig{Depth: 2, Workers: 1}
+	conf.Sitemap.Enabled = true
+	conf.Sitemap.MaxUrls = 2
+	s := newTestCrawlerService(srv, conf)
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+	close(fetched)
+
+	if report := res.SitemapXml; report.ListedCount != 2 || len(report.OrphanPages) != 2 || report.OrphanPages[1] != srv.URL+"/b" {
+		t.Errorf("duplicate and out of scope urls should not use limit, got %+v", report)
+	}
+	for f := range fetched {
+		if f != "/first.xml" {
+			t.Errorf("sitemap %s should not be fetched when limit is reached", f)
+		}
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	body, err := readLimited(strings.NewReader("12345"), 5)
+	if err != nil || string(body) != "12345" {
+		t.Errorf("expected full body, got %q err: %v", body, err)
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(make([]byte, 1024*1024))
+	gz.Close()
+
+	r, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readLimited(r, 1024); err == nil {
+		t.Error("decompressed body over limit should be an error")
+	}
+}
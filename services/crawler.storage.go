@@ -0,0 +1,280 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-link-crawler/config"
+	"go-link-crawler/log"
+	"go-link-crawler/utils"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	VisitedMap   = "map"
+	VisitedBloom = "bloom"
+
+	defaultFalsePositiveRate = 0.001
+	defaultExpectedUrls      = 100000
+)
+
+// storedPage is line of pages file
+type storedPage struct {
+	Url string `json:"url"`
+	pageResult
+}
+
+// pageStore writes data of crawled pages to jsonl file instead of memory
+type pageStore struct {
+	path string
+	file *os.File
+	w    *bufio.Writer
+	enc  *json.Encoder
+	err  error
+	mux  sync.Mutex
+}
+
+// newPageStore opens pages file at path in append mode, if path is empty new <domain>.pages.jsonl file is created in dir,
+// number is added to its name if the file exists, so seeds of the same host and earlier crawls do not overwrite it
+func newPageStore(dir, domain, path string) (*pageStore, error) {
+	var f *os.File
+	var err error
+	if path != "" {
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	} else {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		for i := 1; ; i++ {
+			name := domain + ".pages.jsonl"
+			if i > 1 {
+				name = fmt.Sprintf("%s.%d.pages.jsonl", domain, i)
+			}
+			path = filepath.Join(dir, name)
+			f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+			if !os.IsExist(err) {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	w := bufio.NewWriter(f)
+	return &pageStore{
+		path: path,
+		file: f,
+		w:    w,
+		enc:  json.NewEncoder(w),
+	}, nil
+}
+
+func (s *pageStore) write(page storedPage) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	if s.err = s.enc.Encode(page); s.err != nil {
+		log.WithTrace("CrawlerService", "pageStore", "write").Errorf("write %s err: %v", s.path, s.err)
+	}
+}
+
+func (s *pageStore) close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if err := s.w.Flush(); err != nil && s.err == nil {
+		s.err = err
+	}
+	if err := s.file.Close(); err != nil && s.err == nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// checkStorageConfig rejects reports which need data that bounded storage does not keep:
+// bloom visited set keeps no found urls and needs pages file, pages file keeps no pages in memory
+func checkStorageConfig(conf config.CrawlerConfig) error {
+	unsupported := func(option string, enabled map[string]bool) error {
+		names := make([]string, 0)
+		for name, ok := range enabled {
+			if ok {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%s cannot be used with %s, their reports need all pages in memory", option, strings.Join(names, ", "))
+	}
+
+	if conf.Storage.Visited == VisitedBloom {
+		if conf.Storage.PagesDir == "" {
+			return errors.New("storage.visited bloom requires storage.pages_dir, pages cannot be kept in memory")
+		}
+		err := unsupported("storage.visited bloom", map[string]bool{
+			"graph":                conf.Graph.Enabled,
+			"analysis":             conf.Analysis.Enabled,
+			"external_check":       conf.ExternalCheck.Enabled,
+			"sitemap":              conf.Sitemap.Enabled,
+			"check_fragments":      conf.CheckFragments,
+			"duplicates":           conf.Duplicates.Enabled,
+			"robots_policy report": conf.RobotsPolicy == robotsPolicyReport,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if conf.Storage.PagesDir != "" {
+		return unsupported("storage.pages_dir", map[string]bool{
+			"graph":           conf.Graph.Enabled,
+			"analysis":        conf.Analysis.Enabled,
+			"seo":             conf.Seo.Enabled,
+			"duplicates":      conf.Duplicates.Enabled,
+			"check_fragments": conf.CheckFragments,
+		})
+	}
+
+	return nil
+}
+
+// initStorage sets visited set and pages file of process by storage config,
+// pagesFile of resumed crawl is appended instead of new file
+func (p *CrawlerProcess) initStorage(pagesFile string) error {
+	if err := checkStorageConfig(p.crawlerService.conf); err != nil {
+		return err
+	}
+
+	conf := p.crawlerService.conf.Storage
+
+	switch conf.Visited {
+	case "", VisitedMap:
+	case VisitedBloom:
+		capacity := orDefault(conf.ExpectedUrls, defaultExpectedUrls)
+		rate := conf.FalsePositiveRate
+		if rate <= 0 {
+			rate = defaultFalsePositiveRate
+		}
+		p.visited = utils.NewScalableBloomFilter(capacity, rate)
+		p.externalVisited = utils.NewScalableBloomFilter(capacity, rate)
+	default:
+		return fmt.Errorf("unknown visited set: %s, use map or bloom", conf.Visited)
+	}
+
+	if conf.PagesDir != "" {
+		store, err := newPageStore(conf.PagesDir, p.uri.Host, pagesFile)
+		if err != nil {
+			return err
+		}
+		p.pages = store
+	}
+
+	return nil
+}
+
+// bounded returns true if visited urls are kept in bloom filter, then per url data are not kept
+// for all found urls: sitemap, external links and referrers of healthy pages
+func (p *CrawlerProcess) bounded() bool {
+	return p.visited != nil
+}
+
+// isVisited checks visited set, it has to be called under p.mux lock
+func (p *CrawlerProcess) isVisited(fullUrl string) bool {
+	if p.bounded() {
+		return p.visited.Test(fullUrl)
+	}
+	_, ok := p.sitemap[fullUrl]
+	return ok
+}
+
+// markVisited adds url to visited set, it returns false if url was visited already,
+// it has to be called under p.mux lock
+func (p *CrawlerProcess) markVisited(fullUrl string, depth int) bool {
+	if p.bounded() {
+		return p.visited.Add(fullUrl)
+	}
+	if _, ok := p.sitemap[fullUrl]; ok {
+		return false
+	}
+	p.sitemap[fullUrl] = depth
+	return true
+}
+
+// unmarkVisited removes url from visited set, bloom filter cannot remove it and url is not crawled,
+// it has to be called under p.mux lock
+func (p *CrawlerProcess) unmarkVisited(fullUrl string) {
+	if !p.bounded() {
+		delete(p.sitemap, fullUrl)
+	}
+}
+
+// addExternal remembers external url, it returns false if url was found already
+func (p *CrawlerProcess) addExternal(fullUrl string) bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	if p.bounded() {
+		if !p.externalVisited.Add(fullUrl) {
+			return false
+		}
+	} else {
+		if p.external[fullUrl] {
+			return false
+		}
+		p.external[fullUrl] = true
+	}
+	p.externalCount++
+	return true
+}
+
+// storePage keeps data of page in memory or writes it to pages file,
+// only pages with error status stay in memory for report of broken links then
+// and robots of pages which are not indexable or followed for robots report of unbounded crawl
+func (p *CrawlerProcess) storePage(link crawlerLink, d crawlerLinkData) {
+	p.mux.Lock()
+	if p.requests == 0 || d.Start.Before(p.firstStart) {
+		p.firstStart = d.Start
+	}
+	if end := d.Start.Add(d.Since); end.After(p.lastEnd) {
+		p.lastEnd = end
+	}
+	p.requests++
+	if d.StatusCode >= http.StatusBadRequest {
+		p.errorPages++
+	}
+
+	if p.bounded() && d.StatusCode >= http.StatusBadRequest {
+		p.addReferrer(link.Url, link.Referrer)
+	}
+	if p.pages == nil || d.StatusCode >= http.StatusBadRequest {
+		p.data[link.Url] = d
+	} else if !p.bounded() && d.Robots != nil && (!d.Robots.Indexable || !d.Robots.Follow) {
+		p.robots[link.Url] = crawlerLinkData{StatusCode: d.StatusCode, Robots: d.Robots}
+	}
+	p.mux.Unlock()
+
+	if p.pages != nil {
+		p.pages.write(storedPage{Url: link.Url, pageResult: newPageResult(d)})
+	}
+}
+
+// closeStorage flushes pages file
+func (p *CrawlerProcess) closeStorage() {
+	if p.pages == nil {
+		return
+	}
+	if err := p.pages.close(); err != nil {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "closeStorage").Errorf("pages file %s err: %v", p.pages.path, err)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"go-link-crawler/config"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckStorageConfig(t *testing.T) {
+	conf := config.CrawlerConfig{}
+	conf.Storage.Visited = VisitedBloom
+	if err := checkStorageConfig(conf); err == nil || !strings.Contains(err.Error(), "requires storage.pages_dir") {
+		t.Errorf("bloom without pages dir should be an error, got %v", err)
+	}
+
+	conf.Storage.PagesDir = "pages"
+	conf.RobotsPolicy = robotsPolicyRespect
+	if err := checkStorageConfig(conf); err != nil {
+		t.Errorf("bloom with pages dir and respected robots should be valid, got %v", err)
+	}
+
+	conf.RobotsPolicy = robotsPolicyReport
+	conf.Graph.Enabled = true
+	conf.ExternalCheck.Enabled = true
+	conf.Sitemap.Enabled = true
+	conf.Duplicates.Enabled = true
+	err := checkStorageConfig(conf)
+	expected := "duplicates, external_check, graph, robots_policy report, sitemap"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("bloom should reject %s, got %v", expected, err)
+	}
+
+	conf = config.CrawlerConfig{}
+	conf.Storage.Visited = VisitedBloom
+	conf.Storage.PagesDir = "pages"
+	conf.CheckFragments = true
+	if err := checkStorageConfig(conf); err == nil || !strings.Contains(err.Error(), "check_fragments") {
+		t.Errorf("bloom with check fragments should be an error, got %v", err)
+	}
+
+	conf = config.CrawlerConfig{RobotsPolicy: robotsPolicyReport}
+	conf.Storage.PagesDir = "pages"
+	if err := checkStorageConfig(conf); err != nil {
+		t.Errorf("pages dir with robots report should be valid, got %v", err)
+	}
+	conf.Seo.Enabled = true
+	if err := checkStorageConfig(conf); err == nil || !strings.Contains(err.Error(), "seo") {
+		t.Errorf("pages dir with seo should be an error, got %v", err)
+	}
+}
+
+func TestPageStoreFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first, err := newPageStore(dir, "site.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newPageStore(dir, "site.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.path != filepath.Join(dir, "site.com.pages.jsonl") || second.path != filepath.Join(dir, "site.com.2.pages.jsonl") {
+		t.Errorf("seeds of the same host should have own files, got %s and %s", first.path, second.path)
+	}
+	first.write(storedPage{Url: "https://site.com/"})
+	first.close()
+	second.close()
+
+	resumed, err := newPageStore(dir, "site.com", first.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed.write(storedPage{Url: "https://site.com/a"})
+	if err := resumed.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(first.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	lines := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("resumed crawl should append to pages file, got %d lines", lines)
+	}
+}
+
+func TestPagesDirRobotsReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hidden" {
+			fmt.Fprint(w, `<html><head><meta name="robots" content="noindex"></head><body></body></html>`)
+			return
+		}
+		fmt.Fprint(w, `<html><body><a href="/hidden">hidden</a></body></html>`)
+	}))
+	defer srv.Close()
+
+	conf := config.CrawlerConfig{Depth: 2, Workers: 1, RobotsPolicy: robotsPolicyReport}
+	conf.Storage.PagesDir = dir
+	s := newTestCrawlerService(srv, conf)
+	defer s.Close()
+
+	p, err := s.Start(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := p.GetResult()
+	if len(res.Pages) != 0 || res.PagesFile == "" {
+		t.Fatalf("pages should be spilled to file, got %d pages", len(res.Pages))
+	}
+	if len(res.Robots.NoindexPages) != 1 || res.Robots.NoindexPages[0] != srv.URL+"/hidden" {
+		t.Errorf("noindex page should be reported, got %v", res.Robots.NoindexPages)
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"go-link-crawler/log"
+	"go-link-crawler/utils"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultMaxUrlLength        = 1024
+	defaultMaxRepeatedSegments = 2
+	defaultMaxQueryVariants    = 100
+	defaultMaxPagesPerPattern  = 500
+
+	trapSamplesCount = 5
+)
+
+const (
+	trapUrlTooLong       = "url-too-long"
+	trapRepeatedSegments = "repeated-path-segments"
+	trapQueryExplosion   = "query-explosion"
+	trapPatternCap       = "pattern-cap"
+	trapSessionId        = "session-id"
+)
+
+// session parameters are often put to urls when cookies are disabled
+var sessionParams = []string{"jsessionid", "phpsessid", "sessionid", "session_id", "sid", "aspsessionid"}
+
+// crawlerTrap is a group of urls that was not crawled
+type crawlerTrap struct {
+	Reason  string   `json:"reason"`
+	Pattern string   `json:"pattern"`
+	Count   int      `json:"count"`
+	Samples []string `json:"samples"`
+}
+
+// trapDetector stops expansion of urls that look like infinite spaces
+type trapDetector struct {
+	queryVariants map[string]int // path -> urls with query
+	patterns      map[string]int // pattern -> urls
+	trapped       map[string]bool
+	traps         map[string]*crawlerTrap
+}
+
+func newTrapDetector() *trapDetector {
+	return &trapDetector{
+		queryVariants: make(map[string]int),
+		patterns:      make(map[string]int),
+		trapped:       make(map[string]bool),
+		traps:         make(map[string]*crawlerTrap),
+	}
+}
+
+// isTrap checks new unique url, it has to be called under p.mux lock
+func (p *CrawlerProcess) isTrap(fullUrl string) bool {
+	conf := p.crawlerService.conf.Traps
+	if !conf.Enabled {
+		return false
+	}
+
+	d := p.traps
+	if d.trapped[fullUrl] {
+		return true
+	}
+
+	u, err := url.Parse(fullUrl)
+	if err != nil {
+		return false
+	}
+
+	pattern := utils.UrlPattern(u)
+	reason := ""
+
+	if len(fullUrl) > orDefault(conf.MaxUrlLength, defaultMaxUrlLength) {
+		reason = trapUrlTooLong
+	} else if hasSessionId(u) {
+		reason = trapSessionId
+	} else if maxSegmentRepeats(u.Path) > orDefault(conf.MaxRepeatedSegments, defaultMaxRepeatedSegments) {
+		reason = trapRepeatedSegments
+	} else if u.RawQuery != "" && d.queryVariants[u.Path] >= orDefault(conf.MaxQueryVariants, defaultMaxQueryVariants) {
+		reason = trapQueryExplosion
+		pattern = u.Host + u.Path + "?*"
+	} else if d.patterns[pattern] >= orDefault(conf.MaxPagesPerPattern, defaultMaxPagesPerPattern) {
+		reason = trapPatternCap
+	}
+
+	if reason == "" {
+		if u.RawQuery != "" {
+			d.queryVariants[u.Path]++
+		}
+		d.patterns[pattern]++
+		return false
+	}
+
+	d.trapped[fullUrl] = true
+
+	key := reason + " " + pattern
+	trap, ok := d.traps[key]
+	if !ok {
+		log.WithTrace("CrawlerService", "CrawlerProcess", "isTrap").Warnf("suspected crawler trap %s: %s", reason, pattern)
+		trap = &crawlerTrap{Reason: reason, Pattern: pattern, Samples: []string{}}
+		d.traps[key] = trap
+	}
+	trap.Count++
+	if len(trap.Samples) < trapSamplesCount {
+		trap.Samples = append(trap.Samples, fullUrl)
+	}
+
+	return true
+}
+
+// uncountTrap reverts counts of url accepted by isTrap which was not queued,
+// it has to be called under p.mux lock
+func (p *CrawlerProcess) uncountTrap(fullUrl string) {
+	if !p.crawlerService.conf.Traps.Enabled {
+		return
+	}
+
+	u, err := url.Parse(fullUrl)
+	if err != nil {
+		return
+	}
+
+	d := p.traps
+	if u.RawQuery != "" && d.queryVariants[u.Path] > 0 {
+		d.queryVariants[u.Path]--
+	}
+	if pattern := utils.UrlPattern(u); d.patterns[pattern] > 0 {
+		d.patterns[pattern]--
+	}
+}
+
+func hasSessionId(u *url.URL) bool {
+	lower := strings.ToLower(u.Path)
+	query := u.Query()
+	for _, name := range sessionParams {
+		if strings.Contains(lower, ";"+name+"=") || query.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSegmentRepeats returns how many times the most frequent path segment occurs
+func maxSegmentRepeats(path string) int {
+	counts := make(map[string]int)
+	res := 0
+	for _, s := range strings.Split(path, "/") {
+		if s == "" {
+			continue
+		}
+		counts[s]++
+		if counts[s] > res {
+			res = counts[s]
+		}
+	}
+	return res
+}
+
+func orDefault(value, def int) int {
+	if value > 0 {
+		return value
+	}
+	return def
+}
+
+func (p *CrawlerProcess) getTraps() []crawlerTrap {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+
+	res := make([]crawlerTrap, 0, len(p.traps.traps))
+	for _, t := range p.traps.traps {
+		res = append(res, *t)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Pattern < res[j].Pattern
+	})
+
+	return res
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"go-link-crawler/config"
+	"strings"
+	"testing"
+)
+
+func newTestTrapProcess(traps config.TrapsConfig) *CrawlerProcess {
+	conf := config.CrawlerConfig{Traps: traps}
+	return &CrawlerProcess{
+		crawlerService: &CrawlerService{conf: conf},
+		traps:          newTrapDetector(),
+	}
+}
+
+func TestTrapHeuristics(t *testing.T) {
+	p := newTestTrapProcess(config.TrapsConfig{Enabled: true, MaxUrlLength: 60, MaxQueryVariants: 2, MaxPagesPerPattern: 3})
+
+	cases := []struct {
+		url  string
+		trap bool
+	}{
+		{"https://site.com/" + strings.Repeat("a", 60), true},
+		{"https://site.com/cart;jsessionid=123", true},
+		{"https://site.com/list?sid=abc", true},
+		{"https://site.com/a/b/a/b/a", true},
+		{"https://site.com/a/b/a/b", false},
+		{"https://site.com/search?q=1", false},
+		{"https://site.com/search?q=2", false},
+		{"https://site.com/search?q=3", true},
+		{"https://site.com/news/1", false},
+		{"https://site.com/news/2", false},
+		{"https://site.com/news/3", false},
+		{"https://site.com/news/4", true},
+		{"https://site.com/news/4", true},
+	}
+	for _, c := range cases {
+		if trap := p.isTrap(c.url); trap != c.trap {
+			t.Errorf("%s expected trap %v, got %v", c.url, c.trap, trap)
+		}
+	}
+
+	traps := p.getTraps()
+	reasons := make(map[string]int)
+	for _, trap := range traps {
+		reasons[trap.Reason] += trap.Count
+	}
+	expected := map[string]int{trapUrlTooLong: 1, trapSessionId: 2, trapRepeatedSegments: 1, trapQueryExplosion: 1, trapPatternCap: 1}
+	for reason, count := range expected {
+		if reasons[reason] != count {
+			t.Errorf("reason %s expected count %d, got %d", reason, count, reasons[reason])
+		}
+	}
+
+	if p := newTestTrapProcess(config.TrapsConfig{}); p.isTrap("https://site.com/a/a/a/a") {
+		t.Error("disabled detector should not find traps")
+	}
+}
+
+func TestUncountTrap(t *testing.T) {
+	p := newTestTrapProcess(config.TrapsConfig{Enabled: true, MaxPagesPerPattern: 1})
+
+	if p.isTrap("https://site.com/page/1") {
+		t.Fatal("the first url of pattern should not be trap")
+	}
+	// url was not queued, so its pattern is not counted
+	p.uncountTrap("https://site.com/page/1")
+	if p.isTrap("https://site.com/page/2") {
+		t.Error("uncounted pattern should accept url again")
+	}
+	if !p.isTrap("https://site.com/page/3") {
+		t.Error("pattern over limit should be trap")
+	}
+}
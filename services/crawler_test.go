@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+	"go-link-crawler/config"
+	"net/http/httptest"
+)
+
+// newTestCrawlerService returns service crawling test server, it has to be closed by test
+func newTestCrawlerService(srv *httptest.Server, conf config.CrawlerConfig) *CrawlerService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &CrawlerService{
+		conf:       conf,
+		httpClient: srv.Client(),
+		extractors: newExtractors(conf.Extract),
+		scheduler:  newScheduler(0, 0),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// statsSignals print stats of running crawls
+var statsSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// statsSignals print stats of running crawls, windows has no user signals
+var statsSignals = []os.Signal{}
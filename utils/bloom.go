@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+const (
+	// bloomGrowth is capacity ratio of the next filter of scalable filter
+	bloomGrowth = 2
+	// bloomTightening is false positive ratio of the next filter, sum of rates of all filters stays below the target rate
+	bloomTightening = 0.5
+)
+
+// bloomFilter is fixed size Bloom filter
+type bloomFilter struct {
+	bits     []uint64
+	m        uint64 // count of bits
+	k        uint64 // count of hashes
+	count    int
+	capacity int
+}
+
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Ceil(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+// bloomHashes returns two hashes of key, i-th index is h1 + i*h2
+func bloomHashes(key string) (uint64, uint64) {
+	a := fnv.New64a()
+	a.Write([]byte(key))
+	b := fnv.New64()
+	b.Write([]byte(key))
+	return a.Sum64(), b.Sum64() | 1
+}
+
+func (f *bloomFilter) test(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		n := (h1 + i*h2) % f.m
+		if f.bits[n/64]&(1<<(n%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *bloomFilter) add(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		n := (h1 + i*h2) % f.m
+		f.bits[n/64] |= 1 << (n % 64)
+	}
+	f.count++
+}
+
+// ScalableBloomFilter is set of strings with bounded memory, it may report key which was never added
+// with probability below FalsePositiveRate, it never misses added key. A new larger filter is added
+// when the last one is full, so count of keys does not have to be known in advance.
+// It is not safe for concurrent use.
+type ScalableBloomFilter struct {
+	filters  []*bloomFilter
+	capacity int
+	fpRate   float64
+	count    int
+}
+
+// NewScalableBloomFilter returns filter with the first filter for capacity keys and target false positive rate
+func NewScalableBloomFilter(capacity int, fpRate float64) *ScalableBloomFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.001
+	}
+
+	return &ScalableBloomFilter{
+		capacity: capacity,
+		fpRate:   fpRate,
+	}
+}
+
+// Test returns true if key is probably in set
+func (s *ScalableBloomFilter) Test(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for _, f := range s.filters {
+		if f.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add puts key to set, it returns false if key is probably in set already
+func (s *ScalableBloomFilter) Add(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for _, f := range s.filters {
+		if f.test(h1, h2) {
+			return false
+		}
+	}
+
+	last := len(s.filters) - 1
+	if last < 0 || s.filters[last].count >= s.filters[last].capacity {
+		capacity := s.capacity
+		rate := s.fpRate * (1 - bloomTightening)
+		if last >= 0 {
+			capacity = s.filters[last].capacity * bloomGrowth
+			rate = s.fpRate * (1 - bloomTightening) * math.Pow(bloomTightening, float64(last+1))
+		}
+		s.filters = append(s.filters, newBloomFilter(capacity, rate))
+		last++
+	}
+
+	s.filters[last].add(h1, h2)
+	s.count++
+
+	return true
+}
+
+// Count returns count of added keys, keys rejected as false positives are not counted
+func (s *ScalableBloomFilter) Count() int {
+	return s.count
+}
+
+// Bytes returns memory used by bits of filters
+func (s *ScalableBloomFilter) Bytes() int {
+	res := 0
+	for _, f := range s.filters {
+		res += len(f.bits) * 8
+	}
+	return res
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScalableBloomFilter(t *testing.T) {
+	f := NewScalableBloomFilter(1000, 0.01)
+
+	for i := 0; i < 20000; i++ {
+		f.Add(fmt.Sprintf("https://example.com/page/%d", i))
+	}
+	for i := 0; i < 20000; i++ {
+		if !f.Test(fmt.Sprintf("https://example.com/page/%d", i)) {
+			t.Fatalf("added key %d is missing", i)
+		}
+	}
+	if f.Add("https://example.com/page/1") {
+		t.Error("Add of existing key should return false")
+	}
+	if len(f.filters) < 2 {
+		t.Errorf("filter should grow, got %d filters", len(f.filters))
+	}
+
+	falsePositives := 0
+	for i := 0; i < 20000; i++ {
+		if f.Test(fmt.Sprintf("https://example.com/other/%d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / 20000; rate > 0.02 {
+		t.Errorf("false positive rate should be about 0.01, got %f", rate)
+	}
+}
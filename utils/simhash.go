@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+const simHashShingle = 3
+
+// SimHash returns 64 bit fingerprint of text built from word shingles,
+// similar texts have fingerprints with small Hamming distance
+func SimHash(text string) uint64 {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(shingle string) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+		for i := uint(0); i < 64; i++ {
+			if sum&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	if len(words) < simHashShingle {
+		add(strings.Join(words, " "))
+	}
+	for i := 0; i+simHashShingle <= len(words); i++ {
+		add(strings.Join(words[i:i+simHashShingle], " "))
+	}
+
+	var res uint64
+	for i := uint(0); i < 64; i++ {
+		if weights[i] > 0 {
+			res |= 1 << i
+		}
+	}
+
+	return res
+}
+
+// HammingDistance returns count of different bits
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
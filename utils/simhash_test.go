@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSimHash(t *testing.T) {
+	words := make([]string, 300)
+	for i := range words {
+		words[i] = fmt.Sprintf("word%d", i)
+	}
+	text := strings.Join(words, " ")
+
+	words[150] = "changed"
+	near := strings.Join(words, " ")
+
+	for i := range words {
+		words[i] = fmt.Sprintf("other%d", i)
+	}
+	other := strings.Join(words, " ")
+
+	if SimHash(text) != SimHash(strings.ToUpper(text)) {
+		t.Error("SimHash should ignore case")
+	}
+
+	if d := HammingDistance(SimHash(text), SimHash(near)); d > 3 {
+		t.Errorf("near duplicate distance should be small, got %d", d)
+	}
+
+	if d := HammingDistance(SimHash(text), SimHash(other)); d <= 3 {
+		t.Errorf("different texts distance should be big, got %d", d)
+	}
+}
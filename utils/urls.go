@@ -5,16 +5,16 @@ import (
 	"go-link-crawler/log"
 	"net/url"
 	"regexp"
+	"sort"
 	"strings"
 )
 
 var (
-	reDomain = regexp.MustCompile(`(?im)https?://(?:www.)?(.+?)(?:/|$)`)
+	reDomain = regexp.MustCompile(`(?im)https?://(?:www\.)?(.+?)(?:/|$)`)
 	reScheme = regexp.MustCompile(`(?im)^([a-z-_.]+):`)
 )
 
 func IsInnerUrl(src string, baseUrl *url.URL) bool {
-	src = strings.TrimLeft(src, "www.")
 	matches := reDomain.FindAllStringSubmatch(src, -1)
 	if len(matches) > 0 && matches[0][1] == baseUrl.Host {
 		return true
@@ -22,6 +22,14 @@ func IsInnerUrl(src string, baseUrl *url.URL) bool {
 	return false
 }
 
+// SplitFragment returns url without fragment and the fragment
+func SplitFragment(src string) (string, string) {
+	if i := strings.Index(src, "#"); i >= 0 {
+		return src[:i], src[i+1:]
+	}
+	return src, ""
+}
+
 func GetUrlScheme(src string) string {
 	matches := reScheme.FindAllStringSubmatch(src, -1)
 	if len(matches) > 0 {
@@ -33,11 +41,26 @@ func GetUrlScheme(src string) string {
 func RelativeUrlToFull(src, curUrl string, baseUrl *url.URL) string {
 	src = strings.TrimSpace(src)
 	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
-		if strings.HasPrefix(src, "//") {
-			src = baseUrl.Scheme + ":" + src
-		} else if strings.HasPrefix(src, "/") { // absolute links
-			src = src[1:]
-			src = baseUrl.Scheme + "://" + baseUrl.Host + "/" + src
+		if strings.HasPrefix(src, "#") { // anchor on current page
+			cur, _ := SplitFragment(curUrl)
+			src = cur + src
+		} else if strings.HasPrefix(src, "?") { // query of current page
+			cur, _ := SplitFragment(curUrl)
+			if i := strings.Index(cur, "?"); i >= 0 {
+				cur = cur[:i]
+			}
+			src = cur + src
+		} else if strings.HasPrefix(src, "/") { // absolute and protocol relative links are on host of current page
+			cu, err := url.Parse(curUrl)
+			if err != nil || cu.Host == "" {
+				cu = baseUrl
+			}
+			ref, err := url.Parse(src)
+			if err != nil {
+				log.WithTrace("utils", "RelativeUrlToFull").Errorf("url.Parse('%s') err: %v", src, err)
+				return ""
+			}
+			src = cu.ResolveReference(ref).String()
 		} else { // relative links
 			cu, err := url.Parse(curUrl)
 			if err != nil {
@@ -67,3 +90,37 @@ func RelativeUrlToFull(src, curUrl string, baseUrl *url.URL) string {
 
 	return src
 }
+
+var (
+	reDigits        = regexp.MustCompile(`\d+`)
+	reNumberSegment = regexp.MustCompile(`^\d+$`)
+	reIdSegment     = regexp.MustCompile(`(?i)^(?:[0-9a-f]{16,}|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+)
+
+// UrlPattern replaces numbers and ids in url path by placeholders and drops query values,
+// so `/news/2019/10/page-3?sort=asc` becomes `/news/{n}/{n}/page-{n}?sort`
+func UrlPattern(u *url.URL) string {
+	segments := strings.Split(u.Path, "/")
+	for i, s := range segments {
+		if reNumberSegment.MatchString(s) {
+			segments[i] = "{n}"
+		} else if reIdSegment.MatchString(s) {
+			segments[i] = "{id}"
+		} else {
+			segments[i] = reDigits.ReplaceAllString(s, "{n}")
+		}
+	}
+
+	res := u.Host + strings.Join(segments, "/")
+
+	names := make([]string, 0)
+	for name := range u.Query() {
+		names = append(names, name)
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		res += "?" + strings.Join(names, "&")
+	}
+
+	return res
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestUrlPattern(t *testing.T) {
+	cases := map[string]string{
+		"https://site.com/news/2019/10/page-3?sort=asc&p=2":            "site.com/news/{n}/{n}/page-{n}?p&sort",
+		"https://site.com/item/5f3a9c2b1d4e6f708192a3b4/":              "site.com/item/{id}/",
+		"https://site.com/s/123e4567-e89b-12d3-a456-426614174000/cart": "site.com/s/{id}/cart",
+		"https://site.com/about":                                       "site.com/about",
+	}
+
+	for raw, expected := range cases {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := UrlPattern(u); p != expected {
+			t.Errorf("pattern of %s expected %s, got %s", raw, expected, p)
+		}
+	}
+}
+
+func TestRelativeUrlToFull(t *testing.T) {
+	base, _ := url.Parse("https://site.com/")
+	cases := []struct {
+		src, cur, expected string
+	}{
+		{"#install", "https://site.com/guide#top", "https://site.com/guide#install"},
+		{"?page=2", "https://site.com/list?page=1", "https://site.com/list?page=2"},
+		{"/about", "https://site.com/guide", "https://site.com/about"},
+		{"//site.com/x", "https://site.com/guide", "https://site.com/x"},
+		{"/about", "https://blog.site.com/post", "https://blog.site.com/about"},
+		{"//cdn.site.com/x", "http://blog.site.com/post", "http://cdn.site.com/x"},
+		{"/a/../b#c", "https://site.com/guide", "https://site.com/b#c"},
+	}
+
+	for _, c := range cases {
+		if res := RelativeUrlToFull(c.src, c.cur, base); res != c.expected {
+			t.Errorf("%s on %s expected %s, got %s", c.src, c.cur, c.expected, res)
+		}
+	}
+}